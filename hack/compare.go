@@ -1,28 +1,34 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 
 	"github.com/cockroachdb/errors"
-	"k8s.io/apimachinery/pkg/util/yaml"
 
 	"github.com/bitoku/difftool/pkg/objdiff"
+	"github.com/bitoku/difftool/pkg/source"
 )
 
-// if we unmarshall yaml directly, int64 is inferred as float64 somehow,
-// so we convert yaml to json first and then unmarshall it
-func unmarshall(data []byte, v any) error {
-	jsonContent, err := yaml.ToJSON(data)
+// unmarshall decodes the yaml or json documents, where multiple documents are the items of a list.
+// They're decoded by source.SplitObjects so that int64 isn't inferred as float64.
+func unmarshall(data []byte, obj *objdiff.Object) error {
+	objs, err := source.SplitObjects(data)
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	err = json.Unmarshal(jsonContent, v)
-	return errors.WithStack(err)
+	switch len(objs) {
+	case 0:
+		return errors.New("no object in the manifest")
+	case 1:
+		*obj = *objs[0]
+	default:
+		obj.APIVersion, obj.Kind, obj.Items = "v1", "List", objs
+	}
+	return nil
 }
 
-func loadYaml(path string, v any) error {
+func loadYaml(path string, v *objdiff.Object) error {
 	file, err := os.ReadFile(path)
 	if err != nil {
 		return errors.WithStack(err)
@@ -30,22 +36,51 @@ func loadYaml(path string, v any) error {
 	return unmarshall(file, v)
 }
 
+// loadRevision loads the manifest at the path as it was at the ref of the git repository in the working directory.
+func loadRevision(path, ref string, v *objdiff.Object) error {
+	data, err := source.ReadFileAt(".", ref, path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return unmarshall(data, v)
+}
+
+// usage:
+//
+//	go run hack/compare.go old.yaml new.yaml
+//	go run hack/compare.go old.json new.json
+//	go run hack/compare.go -git path/to/manifest.yaml v1.0.0 main
 func main() {
 	//diffOpts := []cmp.Option{objdiff.IgnoreMapEntries(target.Ignore)}
 	var obj1, obj2 objdiff.Object
-	err := loadYaml(os.Args[1], &obj1)
-	if err != nil {
-		panic(err.Error())
+	var err error
+	if os.Args[1] == "-git" {
+		err = loadRevision(os.Args[2], os.Args[3], &obj1)
+		if err == nil {
+			err = loadRevision(os.Args[2], os.Args[4], &obj2)
+		}
+	} else {
+		err = loadYaml(os.Args[1], &obj1)
+		if err == nil {
+			err = loadYaml(os.Args[2], &obj2)
+		}
 	}
-	err = loadYaml(os.Args[2], &obj2)
 	if err != nil {
 		panic(err.Error())
 	}
 	if obj1.IsList() {
-		p, d := objdiff.DiffList(obj1.Items, obj2.Items)
-		fmt.Printf("%v\n", p)
-		fmt.Printf("%v\n", d)
+		results, err := objdiff.DiffList(obj1.Items, obj2.Items)
+		if err != nil {
+			panic(err.Error())
+		}
+		for _, r := range results {
+			fmt.Println(r)
+		}
 	} else {
-		fmt.Println(objdiff.DiffObj(&obj1, &obj2))
+		d, err := objdiff.DiffObj(&obj1, &obj2)
+		if err != nil {
+			panic(err.Error())
+		}
+		fmt.Println(d)
 	}
 }
@@ -1,11 +1,18 @@
 package main
 
 import (
+	"os"
+
+	"github.com/cockroachdb/errors"
+
 	"github.com/bitoku/difftool/pkg/cli"
 )
 
 func main() {
 	err := cli.Run()
+	if errors.Is(err, cli.ErrDiffFound) {
+		os.Exit(1)
+	}
 	if err != nil {
 		panic(err.Error())
 	}
@@ -1,33 +1,99 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/fatih/color"
 	"github.com/google/go-cmp/cmp"
 	configv1 "github.com/openshift/client-go/config/clientset/versioned/typed/config/v1"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/json"
 	"k8s.io/apimachinery/pkg/util/yaml"
-	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/util/homedir"
+	"k8s.io/utils/strings/slices"
 
 	"github.com/bitoku/difftool/pkg/objdiff"
+	"github.com/bitoku/difftool/pkg/report"
+	"github.com/bitoku/difftool/pkg/source"
 	"github.com/bitoku/difftool/pkg/util"
 )
 
 type Target struct {
 	v1.TypeMeta `json:",inline"`
-	Manifest    string   `json:"manifest"`
-	Ignore      []string `json:"ignore"`
+	Manifest    string             `json:"manifest"`
+	Ignore      []string           `json:"ignore"`
+	IgnoreIf    []*VersionedIgnore `json:"ignoreIf"`
+	Coerce      []string           `json:"coerce"`
+	Optional    []string           `json:"optional"`
+	// Booleans is the keys where "true" and true, and "false" and false are equal
+	Booleans []string `json:"booleans"`
+	// IgnoreInEach is the fields ignored in every element of the slices
+	IgnoreInEach []*EachIgnore `json:"ignoreInEach"`
+	// IgnoreMatching is the keys ignored if the values on both sides match the patterns
+	IgnoreMatching []*MatchingIgnore `json:"ignoreMatching"`
+	// Approximate is the keys whose numbers are equal within the tolerance
+	Approximate []*ApproximateNumber `json:"approximate"`
+	// MergeKeys compares the elements of the known lists like containers by their merge keys rather than their indices
+	MergeKeys bool `json:"mergeKeys"`
+	// ListKeys is the merge keys of more lists like the ones of custom resources, compared by them as well
+	ListKeys map[string][]string `json:"listKeys"`
+	// Resource is a resource as kubectl takes like "deploy" or "deployments.apps", used if apiVersion and kind aren't set
+	Resource string `json:"resource"`
+	// EmbeddedJSON compares json documents in ConfigMap and Secret data as structures
+	EmbeddedJSON bool `json:"embeddedJSON"`
+	// EmbeddedYAML is the ConfigMap and Secret data keys whose values are compared as yaml
+	EmbeddedYAML []string `json:"embeddedYAML"`
+	// DataLines compares multi-line values of ConfigMap and Secret data line by line
+	DataLines bool `json:"dataLines"`
+	// KeysOnly compares only the keys of ConfigMap and Secret data, not the values
+	KeysOnly bool `json:"keysOnly"`
+	// ReplicaSelector is the label selector of the objects which the manifest is compared with as a template
+	ReplicaSelector string `json:"replicaSelector"`
+	// Selector is the label selector of the one object which the manifest without a name is compared with
+	Selector string `json:"selector"`
+}
+
+// EachIgnore is the fields ignored in every element of the slice at the key.
+type EachIgnore struct {
+	Slice  string   `json:"slice"`
+	Fields []string `json:"fields"`
+}
+
+// MatchingIgnore is a key ignored if the values on both sides match the regular expression.
+type MatchingIgnore struct {
+	Key     string `json:"key"`
+	Pattern string `json:"pattern"`
+}
+
+// ApproximateNumber is a key whose numbers are equal if they differ by the fraction of the smaller one or the margin at most.
+type ApproximateNumber struct {
+	Key      string  `json:"key"`
+	Fraction float64 `json:"fraction"`
+	Margin   float64 `json:"margin"`
+}
+
+// VersionedIgnore is the keys ignored only when the kubernetes version of the cluster matches the constraint.
+type VersionedIgnore struct {
+	Version string   `json:"version"`
+	Keys    []string `json:"keys"`
 }
 
 // if we unmarshall yaml directly, int64 is inferred as float64 somehow,
@@ -53,7 +119,7 @@ func getAvailableVersions(dir string) []*util.Version {
 	dirEntry, _ := os.ReadDir(dir)
 	var versions []*util.Version
 	for _, v := range dirEntry {
-		version, err := util.ParseVersion(v.Name())
+		version, err := util.ParseVersionStrict(v.Name())
 		if err != nil {
 			_, _ = fmt.Fprintf(os.Stdout, "warning: there is a directory whose name is not a ocp version.: %s", v.Name())
 			continue
@@ -78,12 +144,201 @@ func fallbackPriority(version *util.Version, available []*util.Version) (out []*
 	return out
 }
 
+// stringsFlag is a flag which can be specified multiple times.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+// parseFetchVersions parses flags like "Widget.example.com=v1beta1".
+func parseFetchVersions(flags []string) (map[schema.GroupKind]string, error) {
+	out := make(map[schema.GroupKind]string)
+	for _, f := range flags {
+		kind, version, ok := strings.Cut(f, "=")
+		if !ok || version == "" {
+			return nil, fmt.Errorf("invalid fetch version %q, it must be like Widget.example.com=v1beta1", f)
+		}
+		out[schema.ParseGroupKind(kind)] = version
+	}
+	return out, nil
+}
+
+// parsePairs parses flags like "staging=prod" into a map from the left to the right.
+func parsePairs(flags []string, name string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, f := range flags {
+		from, to, ok := strings.Cut(f, "=")
+		if !ok || from == "" {
+			return nil, fmt.Errorf("invalid %s %q, it must be like from=to", name, f)
+		}
+		out[from] = to
+	}
+	return out, nil
+}
+
+// parseSubresources parses flags like "Deployment.apps=scale".
+func parseSubresources(flags []string) (map[schema.GroupKind]string, error) {
+	out := make(map[schema.GroupKind]string)
+	for _, f := range flags {
+		kind, subresource, ok := strings.Cut(f, "=")
+		if !ok || subresource == "" {
+			return nil, fmt.Errorf("invalid subresource %q, it must be like Deployment.apps=scale", f)
+		}
+		out[schema.ParseGroupKind(kind)] = subresource
+	}
+	return out, nil
+}
+
+// ErrDiffFound is returned by Run when there is a result of the classes to fail on.
+var ErrDiffFound = errors.New("diff found")
+
+func parseClasses(s string) (map[objdiff.Class]bool, error) {
+	out := make(map[objdiff.Class]bool)
+	for _, c := range strings.Split(s, ",") {
+		switch class := objdiff.Class(strings.TrimSpace(c)); class {
+		case objdiff.ClassChanged, objdiff.ClassMissing, objdiff.ClassExtra, objdiff.ClassTerminating, objdiff.ClassForbidden, objdiff.ClassProgressing, objdiff.ClassRejected:
+			out[class] = true
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown class %q", c)
+		}
+	}
+	return out, nil
+}
+
+// parseSince parses either a time in RFC3339 or a duration before now like "24h".
+func parseSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errors.WithStack(err)
+	}
+	return t, nil
+}
+
+// parseNamePattern parses a regular expression enclosed in slashes like "/^myapp-(web|api)$/",
+// or a glob matching the whole name like "myapp-*" where "*" matches any string and "?" any character.
+func parseNamePattern(s string) (*regexp.Regexp, error) {
+	if len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		pattern, err := regexp.Compile(s[1 : len(s)-1])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return pattern, nil
+	}
+	glob := regexp.QuoteMeta(s)
+	glob = strings.ReplaceAll(glob, `\*`, ".*")
+	glob = strings.ReplaceAll(glob, `\?`, ".")
+	return regexp.MustCompile("^" + glob + "$"), nil
+}
+
 type Options struct {
-	Kubeconfig  string
-	Target      string
-	ManifestDir string
-	Version     *util.Version
-	Fallback    bool
+	Kubeconfig    string
+	ContextA      string
+	ContextB      string
+	GitRepo       string
+	GitRef        string
+	Render        string
+	Doc           int
+	DocKind       string
+	DocName       string
+	RenderPath    string
+	HelmRelease   string
+	HelmNamespace string
+	As            string
+	AsGroups      []string
+	Target        string
+	ManifestDir   string
+	Version       *util.Version
+	Fallback      bool
+	Perspective   objdiff.Perspective
+	Since         string
+	Out           string
+	Format        report.Format
+	ScriptDelete  bool
+	Subresources  map[schema.GroupKind]string
+	FetchVersions map[schema.GroupKind]string
+	FailOn        map[objdiff.Class]bool
+
+	// RemapNamespaces and RemapSuffixes remap the identities of the objects of ContextA to the ones of ContextB
+	RemapNamespaces map[string]string
+	RemapSuffixes   map[string]string
+
+	// Baseline is the directory of the manifests Compared is compared with offline
+	Baseline string
+	Compared string
+
+	// CompareVersion is the version which the objects in the cluster are compared at with the version of the manifests
+	CompareVersion string
+
+	ModifiedSince           time.Time
+	ModifiedSinceAnnotation string
+	PartialFetch            bool
+	Protobuf                bool
+	PageSize                int64
+	StructuredMerge         bool
+	InformerCache           bool
+	InformerResync          time.Duration
+	FieldManager            string
+	KeepManagedFields       bool
+	CleanObjects            bool
+	Fields                  []string
+	DataSummary             bool
+	MaxDepth                int
+	ExplainDiff             bool
+	ExplainOptions          bool
+	DryRunCreate            bool
+	IgnoreOwnerUIDs         bool
+	IgnoreGenerated         bool
+	IncludeRemote           bool
+	CheckGeneration         bool
+
+	Inventory      []string
+	InventoryStubs bool
+	Hash           bool
+
+	Watch          bool
+	Concurrency    int
+	NoConcurrency  bool
+	Progress       bool
+	CheckSchema    bool
+	IdentityHeader bool
+
+	DiffContext  int
+	InlineValues bool
+	SortOutput   bool
+	DriftScores  bool
+	SortByDrift  bool
+
+	RecordVersions string
+	PinVersions    map[string]string
+
+	ResourceCache string
+
+	RevealSecrets  bool
+	SensitiveKinds []schema.GroupKind
+	SensitivePaths []string
+
+	IgnoredAnnotations        []string
+	IgnoredAnnotationPrefixes []string
+
+	ExcludedNamespaces []string
+	AllNamespaces      bool
+	IntersectionOnly   bool
+	NamePattern        *regexp.Regexp
+	Transform          *objdiff.Transform
+
+	Formatter *objdiff.Formatter
+	Template  *template.Template
+	Verdicts  bool
 }
 
 func getOpts() (*Options, error) {
@@ -92,27 +347,272 @@ func getOpts() (*Options, error) {
 		kubeconfigDefault = filepath.Join(home, ".kube", "config")
 	}
 	kubeconfig := flag.String("kubeconfig", kubeconfigDefault, "absolute path to the kubeconfig file")
+	compareVersion := flag.String("compare-version", "", "compare the objects of the targets in the cluster at the version of the manifests with the ones at the version like v1, instead of the manifests")
+	contextA := flag.String("context-a", "", "context of the kubeconfig whose objects of the targets are compared with the cluster instead of the manifests")
+	var remapNamespaces, remapSuffixes stringsFlag
+	flag.Var(&remapNamespaces, "remap-namespace", "remap the namespace of the objects of --context-a like staging=prod before pairing them with the cluster. can be specified multiple times")
+	contextNamespaceMap := flag.String("context-namespace-map", "", "comma separated mapping of the namespaces of --context-a to the ones of the cluster like app-staging=app-prod,web-staging=web-prod, added to --remap-namespace. the others are paired as they are")
+	flag.Var(&remapSuffixes, "remap-suffix", "remap the suffix of the names of the objects of --context-a like -staging=-prod before pairing them with the cluster. can be specified multiple times")
+	contextB := flag.String("context-b", "", "context of the kubeconfig of the cluster. the current context by default")
+	gitRepo := flag.String("git-repo", "", "path or url of the git repository to read the manifests rendered by --render from")
+	gitRef := flag.String("git-ref", "HEAD", "ref of --git-repo to read the manifests from")
+	render := flag.String("render", "", "render the manifests in --render-path with kustomize or helm and compare them with the cluster")
+	doc := flag.Int("doc", -1, "compare only the rendered document at the index from 0")
+	docKind := flag.String("kind", "", "compare only the rendered documents of the kind")
+	docName := flag.String("name", "", "compare only the rendered documents of the name")
+	renderPath := flag.String("render-path", ".", "path of the kustomization or the chart to render, in --git-repo if it's given")
+	helmRelease := flag.String("helm-release", "", "compare the manifest of the deployed helm release of the name with the cluster")
+	helmNamespace := flag.String("helm-namespace", "default", "namespace of --helm-release")
+	as := flag.String("as", "", "username to impersonate for the requests to the cluster")
+	var asGroups stringsFlag
+	flag.Var(&asGroups, "as-group", "group to impersonate for the requests to the cluster. can be specified multiple times")
 	target := flag.String("target", "", "path to the target list yaml")
 	manifest := flag.String("manifest", "", "path to the directory of default manifests")
 	version := flag.String("cluster-version", "", "cluster version. auto detect by default")
 	fallback := flag.Bool("fallback", true, "fallback when the specified version is not available")
+	perspective := flag.String("perspective", string(objdiff.PerspectiveManifest), "the baseline of the diff: manifest or cluster")
+	since := flag.String("since", "", "path to a snapshot directory of the cluster to compare with instead of the manifests")
+	out := flag.String("out", "", "path to the file to write the report to")
+	format := flag.String("format", "", "format of the report: plain, json, markdown, script or kubectl. inferred from the extension of --out by default")
+	scriptDelete := flag.Bool("script-delete", false, "delete the objects which exist in the cluster but not in the manifests in the script report")
+	failOn := flag.String("fail-on", "changed,missing,rejected", "comma separated classes of the results to exit with 1: changed, missing, extra, terminating, forbidden, progressing and rejected")
+	modifiedSince := flag.String("modified-since", "", "skip remote objects older than the time in RFC3339 or the duration like 24h")
+	modifiedSinceAnnotation := flag.String("modified-since-annotation", "", "annotation of the time in RFC3339 used by --modified-since instead of the creation timestamp")
+	concurrency := flag.Int("concurrency", 1, "number of targets compared at the same time. the results are printed in the order of the target list regardless, not sorted by the identities of the objects unlike --sort-output")
+	noConcurrency := flag.Bool("no-concurrency", false, "compare the targets one by one in the main goroutine for debugging, listing the pages of lists in it as well, which is deterministic and prints the same as --concurrency")
+	ignoreManagedFields := flag.Bool("ignore-managed-fields", true, "ignore metadata.managedFields for the extractors comparing the metadata")
+	keepManagedFields := flag.Bool("keep-managed-fields", false, "compare metadata.managedFields regardless of --ignore-managed-fields, for debugging server-side apply")
+	fieldManager := flag.String("field-manager", "", "compare only the fields owned by the field manager in the managedFields of the objects in the cluster")
+	progress := flag.Bool("progress", false, "print the progress of the targets and the objects fetched and compared to stderr")
+	fields := flag.String("fields", "", "comma separated top-level fields compared instead of the spec or the data: spec, data and status")
+	checkGeneration := flag.Bool("check-generation", false, "report the objects whose status.observedGeneration or generation is older than their generation or the "+objdiff.ExpectedGenerationAnnotation+" annotation as progressing")
+	includeRemote := flag.Bool("include-remote", false, "print the object in the cluster after the diff of each changed object, with the sensitive values redacted")
+	ignoreGenerated := flag.Bool("ignore-generated", false, "ignore the fields the server generates like the clusterIP and the node ports of Services when only one side has them, which are the ones the manifests don't specify")
+	ignoreOwnerUIDs := flag.Bool("ignore-owner-uids", false, "compare only the kinds, the names and the controller flags of the ownerReferences declared in the manifests")
+	dryRunCreate := flag.Bool("dry-run-create", false, "create the objects missing from the cluster with server-side dry-run, reporting the ones the server would reject as rejected")
+	explainOptions := flag.Bool("explain-options", false, "print the options applied to the comparison of each object and the paths each of them hides")
+	explainDiff := flag.Bool("explain-diff", false, "append the likely cause of every differing path to the diffs, like defaulted by server, managed by another field manager or genuine drift")
+	maxDepth := flag.Int("max-depth", 0, "show the differing paths down to the number of keys, collapsing the deeper ones into \"subtree changed\". 0 shows every leaf")
+	dataSummary := flag.Bool("data-summary", false, "show the diffs of ConfigMap and Secret data as the changed, added and removed keys with their counts")
+	cleanObjects := flag.Bool("clean-objects", false, "compare the objects without the fields set by the server like resourceVersion and managedFields, for the extractors comparing the metadata")
+	pageSize := flag.Int64("page-size", 0, "number of objects listed in a request, comparing each page of lists while the following one is listed. 0 lists all of them at once")
+	protobuf := flag.Bool("protobuf", false, "fetch the objects of the built-in kinds as protobuf, which is faster for large lists. custom resources are fetched as json")
+	structuredMerge := flag.Bool("structured-merge", false, "compare the kinds in the OpenAPI schema of the cluster like server-side apply, matching list items like containers by their keys. targets with go-cmp options like ignore are compared with go-cmp")
+	informerCache := flag.Bool("informer-cache", false, "serve the objects in the cluster from informers caching the resources, which is faster for --watch against large kinds")
+	informerResync := flag.Duration("informer-resync", 0, "period the informers of --informer-cache resync at. 0 never resyncs")
+	partialFetch := flag.Bool("partial-fetch", false, "list the metadata first and fetch only the objects modified since --modified-since in full")
+	var inventory stringsFlag
+	flag.Var(&inventory, "inventory", "list the objects in the cluster of the resource like deploy instead of diffing. can be specified multiple times")
+	hash := flag.Bool("hash", false, "print the hashes of the comparable content of the objects in the manifests instead of diffing")
+	inventoryStubs := flag.Bool("inventory-stubs", false, "print the objects of --inventory as yaml stubs of manifests")
+	var ignoredAnnotations stringsFlag
+	flag.Var(&ignoredAnnotations, "ignore-annotation", "ignore the annotation wherever it is in addition to the rollout annotations. can be specified multiple times")
+	var ignoredAnnotationPrefixes stringsFlag
+	flag.Var(&ignoredAnnotationPrefixes, "ignore-annotation-prefix", "ignore the annotations with the prefix like example.com/ wherever they are in addition to the annotations of tools. can be specified multiple times")
+	defaultIgnoredAnnotationPrefixes := flag.Bool("ignore-tool-annotations", true, "ignore the annotations tools set for their bookkeeping by the prefixes like "+strings.Join(objdiff.DefaultIgnoredAnnotationPrefixes, ", "))
+	defaultIgnoredAnnotations := flag.Bool("ignore-rollout-annotations", true, "ignore the annotations of rollouts like "+objdiff.DefaultIgnoredAnnotations[0])
+	transform := flag.String("transform", "", "jq expression transforming the objects on both sides before they're compared like 'del(.spec.replicas)', which must yield an object")
+	namePattern := flag.String("name-pattern", "", "compare only the objects of lists whose names match the glob like \"myapp-*\", or the regular expression enclosed in slashes like \"/^myapp-(web|api)$/\"")
+	intersectionOnly := flag.Bool("intersection-only", false, "report only the objects existing both in the manifests and the cluster, without missing and extra ones")
+	watch := flag.Bool("watch", false, "keep diffing every time the objects in the cluster change until interrupted")
+	diffContext := flag.Int("diff-context", -1, "number of unchanged lines kept around each change in diffs. negative keeps all of them")
+	driftScores := flag.Bool("drift-scores", false, "show the drift score of each changed object, which is the number of the differing paths weighted by their severities")
+	sortByDrift := flag.Bool("sort-by-drift", false, "sort the results by the drift scores with the most drifted objects first. it implies --drift-scores")
+	sortOutput := flag.Bool("sort-output", false, "sort the results by the kind, the namespace and then the name instead of the order they're compared in")
+	inlineValues := flag.Bool("inline-values", true, "show long values in diffs in full. they're truncated to 120 characters otherwise")
+	resourceCache := flag.String("resource-cache", "", "path to the file caching the resources of the kinds across runs. it's created if it doesn't exist")
+	recordVersions := flag.String("record-versions", "", "path to the file to write the resourceVersions of the compared objects to")
+	pinVersions := flag.String("pin-versions", "", "path to the file written by --record-versions to compare with the objects at those versions")
+	revealSecrets := flag.Bool("reveal-secrets", false, "show the values of Secrets and the sensitive kinds and paths, which are redacted by default")
+	var sensitiveKinds, sensitivePaths stringsFlag
+	flag.Var(&sensitiveKinds, "sensitive-kind", "redact every value of the kind like Credential.example.com as well as Secrets. can be specified multiple times")
+	flag.Var(&sensitivePaths, "sensitive-path", "redact the values at the path of any kind like env.0.value. can be specified multiple times")
+	identityHeader := flag.Bool("identity-header", false, "prepend the identity of the object to the diff of a single object as well as lists")
+	checkSchema := flag.Bool("check-schema", false, "also report fields of custom resources in the cluster deviating from the defaults or violating the schema of the CRD")
+	allNamespaces := flag.Bool("all-namespaces", true, "compare lists with the objects in all namespaces, reporting the ones in the namespaces not in the manifests as extra. only the namespaces of the items are listed with --all-namespaces=false")
+	var excludedNamespaces stringsFlag
+	flag.Var(&excludedNamespaces, "exclude-namespace", "skip objects in the namespace in lists unless it's in the manifests. can be specified multiple times")
+	excludeSystemNamespaces := flag.Bool("exclude-system-namespaces", false, "skip objects in "+strings.Join(objdiff.SystemNamespaces, ", ")+" in lists unless they're in the manifests")
+	missingMessage := flag.String("missing-message", objdiff.DefaultMissingMessage, "template of the message of an object missing from the cluster")
+	extraMessage := flag.String("extra-message", objdiff.DefaultExtraMessage, "template of the message of an object which exists in the cluster but not in the manifests")
+	verdicts := flag.Bool("verdicts", false, "print what kubectl apply would do to each object in the manifests: created, configured or unchanged")
+	templateFlag := flag.String("template", "", "text/template of each result printed instead of the diffs, or the built-in one: oneline or detailed")
+	var fetchVersions stringsFlag
+	flag.Var(&fetchVersions, "fetch-version", "fetch the kind from the cluster at the version like Widget.example.com=v1beta1 instead of the version of the manifests. can be specified multiple times")
+	var subresources stringsFlag
+	flag.Var(&subresources, "subresource", "fetch the kind from the subresource like Deployment.apps=scale. can be specified multiple times")
+	baseline := flag.String("baseline", "", "compare the manifests in the directory of the argument with the ones in this directory offline, pairing the objects by their identities")
 	flag.Parse()
+	if *baseline != "" && flag.NArg() != 1 {
+		return nil, fmt.Errorf("--baseline takes the directory to compare as the argument, but %d are given", flag.NArg())
+	}
 
 	// validate options
-	if *kubeconfig == "" {
+	if *kubeconfig == "" && *baseline == "" {
 		return nil, fmt.Errorf("--kubeconfig option is required")
 	}
-	if *target == "" {
+	// --inventory lists the cluster, which needs neither targets nor manifests
+	if *target == "" && *render == "" && *helmRelease == "" && *baseline == "" && len(inventory) == 0 {
 		return nil, fmt.Errorf("--target option is required")
 	}
-	if *manifest == "" {
-		return nil, fmt.Errorf("--manifest option is required")
+	if *doc >= 0 || *docKind != "" || *docName != "" {
+		// targets are compared as a whole, so the selection would be silently ignored for them
+		if *target != "" {
+			return nil, fmt.Errorf("--doc, --kind and --name can't be combined with --target")
+		}
+		if *render == "" && *helmRelease == "" {
+			return nil, fmt.Errorf("--doc, --kind and --name select the documents of --render or --helm-release")
+		}
+	}
+	if *compareVersion != "" && *contextA != "" {
+		return nil, fmt.Errorf("--compare-version can't be combined with --context-a")
+	}
+	if *pageSize < 0 {
+		return nil, fmt.Errorf("--page-size must not be negative")
+	}
+	if *maxDepth < 0 {
+		return nil, fmt.Errorf("--max-depth must not be negative")
+	}
+	if *concurrency < 1 {
+		return nil, fmt.Errorf("--concurrency must be positive")
+	}
+	if *noConcurrency && *concurrency > 1 {
+		return nil, fmt.Errorf("--no-concurrency can't be used with --concurrency")
+	}
+	if *noConcurrency && *watch {
+		return nil, fmt.Errorf("--no-concurrency can't be used with --watch, which watches the targets at the same time")
+	}
+	if *as == "" && len(asGroups) != 0 {
+		return nil, fmt.Errorf("--as option is required to impersonate groups")
+	}
+	if *manifest == "" && *since == "" && *render == "" && *helmRelease == "" && *baseline == "" && len(inventory) == 0 {
+		return nil, fmt.Errorf("--manifest, --since, --render or --helm-release option is required")
+	}
+
+	parsedPerspective, err := objdiff.ParsePerspective(*perspective)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't parse perspective")
+	}
+
+	parsedFailOn, err := parseClasses(*failOn)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't parse fail-on")
+	}
+
+	var parsedModifiedSince time.Time
+	if *modifiedSince != "" {
+		parsedModifiedSince, err = parseSince(*modifiedSince)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't parse modified-since")
+		}
+	}
+
+	var parsedNamePattern *regexp.Regexp
+	if *namePattern != "" {
+		parsedNamePattern, err = parseNamePattern(*namePattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't parse name-pattern")
+		}
+	}
+
+	var parsedTransform *objdiff.Transform
+	if *transform != "" {
+		parsedTransform, err = objdiff.CompileTransform(*transform)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't parse transform")
+		}
+	}
+
+	parsedFetchVersions, err := parseFetchVersions(fetchVersions)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	parsedRemapNamespaces, err := parsePairs(remapNamespaces, "namespace remap")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if *contextNamespaceMap != "" {
+		namespaceMap, err := parsePairs(strings.Split(*contextNamespaceMap, ","), "namespace map")
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for from, to := range namespaceMap {
+			if ns, ok := parsedRemapNamespaces[from]; ok && ns != to {
+				return nil, fmt.Errorf("namespace %s is mapped to both %s and %s", from, ns, to)
+			}
+			parsedRemapNamespaces[from] = to
+		}
+	}
+	parsedRemapSuffixes, err := parsePairs(remapSuffixes, "suffix remap")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	parsedSubresources, err := parseSubresources(subresources)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if *excludeSystemNamespaces {
+		excludedNamespaces = append(excludedNamespaces, objdiff.SystemNamespaces...)
+	}
+
+	formatter, err := objdiff.NewFormatter(*missingMessage, *extraMessage)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var parsedFields []string
+	if *fields != "" {
+		parsedFields = strings.Split(*fields, ",")
+	}
+
+	var parsedIgnoredAnnotations []string
+	if *defaultIgnoredAnnotations {
+		parsedIgnoredAnnotations = append(parsedIgnoredAnnotations, objdiff.DefaultIgnoredAnnotations...)
+	}
+	parsedIgnoredAnnotations = append(parsedIgnoredAnnotations, ignoredAnnotations...)
+	var parsedIgnoredAnnotationPrefixes []string
+	if *defaultIgnoredAnnotationPrefixes {
+		parsedIgnoredAnnotationPrefixes = append(parsedIgnoredAnnotationPrefixes, objdiff.DefaultIgnoredAnnotationPrefixes...)
+	}
+	parsedIgnoredAnnotationPrefixes = append(parsedIgnoredAnnotationPrefixes, ignoredAnnotationPrefixes...)
+
+	var parsedTemplate *template.Template
+	if *templateFlag != "" {
+		parsedTemplate, err = report.ParseTemplate(*templateFlag)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
 	}
 
-	var (
-		parsedVersion *util.Version
-		err           error
-	)
+	var parsedFormat report.Format
+	if *format != "" {
+		parsedFormat, err = report.ParseFormat(*format)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't parse format")
+		}
+	}
+
+	parsedSensitiveKinds := make([]schema.GroupKind, 0, len(sensitiveKinds))
+	for _, k := range sensitiveKinds {
+		parsedSensitiveKinds = append(parsedSensitiveKinds, schema.ParseGroupKind(k))
+	}
+
+	if *render != "" {
+		if _, err := source.ParseRenderer(*render); err != nil {
+			return nil, errors.Wrap(err, "couldn't parse render")
+		}
+	}
+
+	var pinnedVersions map[string]string
+	if *pinVersions != "" {
+		err = loadYaml(*pinVersions, &pinnedVersions)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't load pinned versions")
+		}
+	}
+
+	var parsedVersion *util.Version
 	if *version != "" {
 		parsedVersion, err = util.ParseVersion(*version)
 		if err != nil {
@@ -121,23 +621,122 @@ func getOpts() (*Options, error) {
 	}
 
 	return &Options{
-		Kubeconfig:  *kubeconfig,
-		Target:      *target,
-		ManifestDir: *manifest,
-		Version:     parsedVersion,
-		Fallback:    *fallback,
+		Kubeconfig:    *kubeconfig,
+		ContextA:      *contextA,
+		ContextB:      *contextB,
+		GitRepo:       *gitRepo,
+		GitRef:        *gitRef,
+		Render:        *render,
+		Doc:           *doc,
+		DocKind:       *docKind,
+		DocName:       *docName,
+		RenderPath:    *renderPath,
+		HelmRelease:   *helmRelease,
+		HelmNamespace: *helmNamespace,
+		As:            *as,
+		AsGroups:      asGroups,
+		Target:        *target,
+		ManifestDir:   *manifest,
+		Version:       parsedVersion,
+		Fallback:      *fallback,
+		Perspective:   parsedPerspective,
+		Since:         *since,
+		Out:           *out,
+		Format:        parsedFormat,
+		ScriptDelete:  *scriptDelete,
+		Subresources:  parsedSubresources,
+		FetchVersions: parsedFetchVersions,
+		FailOn:        parsedFailOn,
+
+		RemapNamespaces: parsedRemapNamespaces,
+		RemapSuffixes:   parsedRemapSuffixes,
+
+		Baseline: *baseline,
+		Compared: flag.Arg(0),
+
+		CompareVersion: *compareVersion,
+
+		ModifiedSince:           parsedModifiedSince,
+		ModifiedSinceAnnotation: *modifiedSinceAnnotation,
+		PartialFetch:            *partialFetch,
+		Protobuf:                *protobuf,
+		PageSize:                *pageSize,
+		StructuredMerge:         *structuredMerge,
+		InformerCache:           *informerCache,
+		InformerResync:          *informerResync,
+		FieldManager:            *fieldManager,
+		KeepManagedFields:       *keepManagedFields || !*ignoreManagedFields,
+		CleanObjects:            *cleanObjects,
+		Fields:                  parsedFields,
+		DataSummary:             *dataSummary,
+		MaxDepth:                *maxDepth,
+		ExplainDiff:             *explainDiff,
+		ExplainOptions:          *explainOptions,
+		DryRunCreate:            *dryRunCreate,
+		IgnoreOwnerUIDs:         *ignoreOwnerUIDs,
+		IgnoreGenerated:         *ignoreGenerated,
+		IncludeRemote:           *includeRemote,
+		CheckGeneration:         *checkGeneration,
+
+		Inventory:      inventory,
+		InventoryStubs: *inventoryStubs,
+		Hash:           *hash,
+
+		Watch:          *watch,
+		Concurrency:    *concurrency,
+		NoConcurrency:  *noConcurrency,
+		Progress:       *progress,
+		CheckSchema:    *checkSchema,
+		IdentityHeader: *identityHeader,
+
+		DiffContext:  *diffContext,
+		InlineValues: *inlineValues,
+		SortOutput:   *sortOutput,
+		DriftScores:  *driftScores || *sortByDrift,
+		SortByDrift:  *sortByDrift,
+
+		RecordVersions: *recordVersions,
+		PinVersions:    pinnedVersions,
+
+		ResourceCache: *resourceCache,
+
+		RevealSecrets:  *revealSecrets,
+		SensitiveKinds: parsedSensitiveKinds,
+		SensitivePaths: sensitivePaths,
+
+		IgnoredAnnotations:        parsedIgnoredAnnotations,
+		IgnoredAnnotationPrefixes: parsedIgnoredAnnotationPrefixes,
+
+		ExcludedNamespaces: excludedNamespaces,
+		AllNamespaces:      *allNamespaces,
+		IntersectionOnly:   *intersectionOnly,
+		NamePattern:        parsedNamePattern,
+		Transform:          parsedTransform,
+
+		Formatter: formatter,
+		Template:  parsedTemplate,
+		Verdicts:  *verdicts,
 	}, nil
 }
 
-func checkTarget(opts *Options, target *Target, version *util.Version, d objdiff.Differ) ([]string, []string, error) {
+func loadManifest(opts *Options, target *Target, version *util.Version) (*objdiff.Object, error) {
 	var obj objdiff.Object
 
+	// a snapshot is a dump of the cluster, so there's no version to choose
+	if opts.Since != "" {
+		err := loadYaml(filepath.Join(opts.Since, target.Manifest), &obj)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return &obj, nil
+	}
+
 	versions := getAvailableVersions(opts.ManifestDir)
 
 	manifest := filepath.Join(opts.ManifestDir, version.String(), target.Manifest)
 	err := loadYaml(manifest, &obj)
 	if err != nil && !os.IsNotExist(errors.Cause(err)) {
-		return nil, nil, errors.Cause(err)
+		return nil, errors.Cause(err)
 	}
 	if os.IsNotExist(errors.Cause(err)) {
 		// fallback if the option is set, otherwise skip the comparison
@@ -147,7 +746,7 @@ func checkTarget(opts *Options, target *Target, version *util.Version, d objdiff
 				manifest = filepath.Join(opts.ManifestDir, v.String(), target.Manifest)
 				err = loadYaml(manifest, &obj)
 				if err != nil && !os.IsNotExist(errors.Cause(err)) {
-					return nil, nil, errors.WithStack(err)
+					return nil, errors.WithStack(err)
 				}
 				if os.IsNotExist(errors.Cause(err)) {
 					continue
@@ -156,13 +755,492 @@ func checkTarget(opts *Options, target *Target, version *util.Version, d objdiff
 				break
 			}
 		} else {
-			return nil, nil, errors.WithStack(err)
+			return nil, errors.WithStack(err)
+		}
+	}
+	sidecar, err := source.LoadSidecar(manifest)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sidecar.Apply(&obj)
+	return &obj, nil
+}
+
+// targetOptions returns the options to compare the target on the version.
+func targetOptions(target *Target, serverVersion *util.Version) ([]cmp.Option, error) {
+	versioned, err := targetVersionedOptions(target)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return objdiff.OptionsFor(serverVersion, versioned...), nil
+}
+
+// targetVersionedOptions returns the options of the target with their names.
+func targetVersionedOptions(target *Target) ([]objdiff.VersionedOption, error) {
+	var versioned []objdiff.VersionedOption
+	if len(target.Ignore) != 0 {
+		versioned = append(versioned, objdiff.VersionedOption{Option: objdiff.IgnoreMapEntries(target.Ignore), Name: "ignore " + strings.Join(target.Ignore, ", ")})
+	}
+	if len(target.Coerce) != 0 {
+		versioned = append(versioned, objdiff.VersionedOption{Option: objdiff.EquateCoercedScalars(target.Coerce...), Name: "coerce " + strings.Join(target.Coerce, ", ")})
+	}
+	if len(target.Booleans) != 0 {
+		// both of them would compare a boolean with a string at the key, which go-cmp rejects as ambiguous
+		for _, k := range target.Booleans {
+			if slices.Contains(target.Coerce, k) {
+				return nil, fmt.Errorf("%s can't be under both coerce and booleans, and coerce equates boolean strings as well", k)
+			}
+		}
+		versioned = append(versioned, objdiff.VersionedOption{Option: objdiff.EquateBooleanStrings(target.Booleans...), Name: "booleans " + strings.Join(target.Booleans, ", ")})
+	}
+	if len(target.Optional) != 0 {
+		versioned = append(versioned, objdiff.VersionedOption{Option: objdiff.OptionalFields(target.Optional...), Name: "optional " + strings.Join(target.Optional, ", ")})
+	}
+	for _, i := range target.IgnoreIf {
+		constraint, err := util.ParseConstraint(i.Version)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		versioned = append(versioned, objdiff.VersionedOption{
+			Constraint: constraint,
+			Option:     objdiff.IgnoreMapEntries(i.Keys),
+			Name:       fmt.Sprintf("ignore %s if %s", strings.Join(i.Keys, ", "), i.Version),
+		})
+	}
+
+	for _, i := range target.IgnoreInEach {
+		for _, f := range i.Fields {
+			versioned = append(versioned, objdiff.VersionedOption{
+				Option: objdiff.IgnoreInEach(i.Slice, f),
+				Name:   fmt.Sprintf("ignore %s in each of %s", f, i.Slice),
+			})
+		}
+	}
+
+	for _, i := range target.IgnoreMatching {
+		pattern, err := regexp.Compile(i.Pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern of %s", i.Key)
+		}
+		versioned = append(versioned, objdiff.VersionedOption{
+			Option: objdiff.IgnoreValuesMatching(i.Key, pattern),
+			Name:   fmt.Sprintf("ignore %s matching %s", i.Key, i.Pattern),
+		})
+	}
+
+	for _, a := range target.Approximate {
+		versioned = append(versioned, objdiff.VersionedOption{
+			Option: objdiff.EquateNumbersWithin(a.Fraction, a.Margin, a.Key),
+			Name:   fmt.Sprintf("approximate %s within %v or %v", a.Key, a.Fraction, a.Margin),
+		})
+	}
+	if target.MergeKeys || len(target.ListKeys) != 0 {
+		versioned = append(versioned, objdiff.VersionedOption{Option: objdiff.MatchByMergeKeys(target.ListKeys), Name: "mergeKeys"})
+	}
+
+	if target.EmbeddedJSON {
+		versioned = append(versioned, objdiff.VersionedOption{Option: objdiff.EquateEmbeddedJSON(), Name: "embeddedJSON"})
+	}
+	if len(target.EmbeddedYAML) != 0 {
+		// both of them would transform json values of the keys, which go-cmp rejects as ambiguous
+		if target.EmbeddedJSON {
+			return nil, errors.New("embeddedYAML can't be combined with embeddedJSON, which compares json values of every key, and embeddedYAML compares json values of the keys as well")
+		}
+		versioned = append(versioned, objdiff.VersionedOption{
+			Option: objdiff.EquateEmbeddedYAML(target.EmbeddedYAML...),
+			Name:   "embeddedYAML " + strings.Join(target.EmbeddedYAML, ", "),
+		})
+	}
+	if target.DataLines {
+		if target.EmbeddedJSON || len(target.EmbeddedYAML) != 0 {
+			return nil, errors.New("dataLines can't be combined with embeddedJSON or embeddedYAML")
+		}
+		versioned = append(versioned, objdiff.VersionedOption{Option: objdiff.DiffDataLines(), Name: "dataLines"})
+	}
+	if target.KeysOnly {
+		versioned = append(versioned, objdiff.VersionedOption{Option: objdiff.CompareDataKeysOnly(), Name: "keysOnly"})
+	}
+	return versioned, nil
+}
+
+type replicaDiffer interface {
+	DiffReplicas(apiVersion, kind string, template *objdiff.Object, selector string, opts ...cmp.Option) ([]objdiff.DiffResult, error)
+}
+
+func checkTarget(target *Target, obj *objdiff.Object, serverVersion *util.Version, d objdiff.Differ) ([]objdiff.DiffResult, error) {
+	diffOpts, err := targetOptions(target, serverVersion)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if target.ReplicaSelector != "" {
+		rd, ok := d.(replicaDiffer)
+		if !ok {
+			return nil, fmt.Errorf("replicaSelector is not supported by %T", d)
 		}
+		return rd.DiffReplicas(target.APIVersion, target.Kind, obj, target.ReplicaSelector, diffOpts...)
+	}
+
+	if target.Selector != "" && !obj.IsList() {
+		obj = withSelector(obj, target.Selector)
 	}
 
 	// check the diff
-	diffOpts := []cmp.Option{objdiff.IgnoreMapEntries(target.Ignore)}
-	return d.Diff(target.APIVersion, target.Kind, &obj, diffOpts...)
+	return d.Diff(target.APIVersion, target.Kind, obj, diffOpts...)
+}
+
+// checkVersions compares the objects of the target in the cluster at the version of the target and at the other version.
+func checkVersions(target *Target, obj *objdiff.Object, version string, serverVersion *util.Version, d *objdiff.Diff) ([]objdiff.DiffResult, error) {
+	diffOpts, err := targetOptions(target, serverVersion)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return d.DiffVersions(target.APIVersion, target.Kind, obj, version, diffOpts...)
+}
+
+// withSelector returns a copy of obj annotated with the selector unless it's already annotated.
+func withSelector(obj *objdiff.Object, selector string) *objdiff.Object {
+	if _, ok := obj.Annotations[objdiff.SelectorAnnotation]; ok {
+		return obj
+	}
+	out := *obj
+	out.Annotations = make(map[string]string, len(obj.Annotations)+1)
+	for k, v := range obj.Annotations {
+		out.Annotations[k] = v
+	}
+	out.Annotations[objdiff.SelectorAnnotation] = selector
+	return &out
+}
+
+type targetResult struct {
+	obj     *objdiff.Object
+	results []objdiff.DiffResult
+}
+
+// diffTarget compares the target and prints the results to w.
+// The object is nil if the target is skipped due to an error.
+// With the context A, its counterpart there is compared with the cluster instead of the manifest.
+func diffTarget(w io.Writer, opts *Options, target *Target, version, serverVersion *util.Version, a, d *objdiff.Diff) targetResult {
+	bold.Fprintf(w, "# %s\n", filepath.Base(target.Manifest))
+
+	obj, err := loadManifest(opts, target, version)
+	if err != nil {
+		warn.Fprintf(os.Stderr, "skipped due to error: %+v\n", err.Error())
+		return targetResult{}
+	}
+	var results []objdiff.DiffResult
+	if a != nil {
+		results, err = checkContexts(opts, target, obj, serverVersion, a, d)
+	} else if opts.CompareVersion != "" {
+		results, err = checkVersions(target, obj, opts.CompareVersion, serverVersion, d)
+	} else {
+		results, err = checkTarget(target, obj, serverVersion, d)
+	}
+	if err != nil {
+		warn.Fprintf(os.Stderr, "skipped due to error: %+v\n", err.Error())
+		return targetResult{}
+	}
+	shapeDiffs(opts, results)
+	printResultsFor(w, opts, needsHeader(opts, target, obj), obj, results)
+	if opts.CheckSchema {
+		printSchemaFindings(w, target, obj, d)
+	}
+	if opts.ExplainOptions {
+		printOptionEffects(w, target, obj, serverVersion, d)
+	}
+	return targetResult{obj: obj, results: results}
+}
+
+// renderManifests renders the manifests in --render-path, which is exported from --git-repo at --git-ref if it's given.
+func renderManifests(opts *Options) ([]*objdiff.Object, error) {
+	renderer, err := source.ParseRenderer(opts.Render)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	dir := opts.RenderPath
+	if opts.GitRepo != "" {
+		dir, err = os.MkdirTemp("", "difftool-")
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		defer os.RemoveAll(dir)
+		err = source.ExportRef(opts.GitRepo, opts.GitRef, opts.RenderPath, dir)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	rendered, err := renderer.Render(dir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	objs, err := source.SplitObjects(rendered)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return selectDocs(opts, objs)
+}
+
+// selectDocs selects the documents by --doc, --kind and --name if any of them is given.
+func selectDocs(opts *Options, objs []*objdiff.Object) ([]*objdiff.Object, error) {
+	if opts.Doc < 0 && opts.DocKind == "" && opts.DocName == "" {
+		return objs, nil
+	}
+	return source.SelectObjects(objs, opts.Doc, opts.DocKind, opts.DocName)
+}
+
+// diffRendered compares each of the rendered objects with the cluster.
+func diffRendered(opts *Options, d objdiff.Differ) ([]objdiff.DiffResult, error) {
+	objs, err := renderManifests(opts)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return diffObjects(opts, d, objs), nil
+}
+
+// diffHelmRelease compares each of the objects of the helm release with the cluster.
+func diffHelmRelease(opts *Options, d *objdiff.Diff) ([]objdiff.DiffResult, error) {
+	objs, err := source.LoadHelmRelease(d, opts.HelmNamespace, opts.HelmRelease)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	objs, err = selectDocs(opts, objs)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return diffObjects(opts, d, objs), nil
+}
+
+// diffObjects compares each of the objects with the cluster, printing the results.
+func diffObjects(opts *Options, d objdiff.Differ, objs []*objdiff.Object) []objdiff.DiffResult {
+	var all []objdiff.DiffResult
+	for _, obj := range objs {
+		bold.Printf("# %s\n", obj)
+		results, err := d.Diff(obj.APIVersion, obj.Kind, obj)
+		if err != nil {
+			warn.Fprintf(os.Stderr, "skipped due to error: %+v\n", err.Error())
+			continue
+		}
+		shapeDiffs(opts, results)
+		all = append(all, results...)
+		printResultsFor(color.Output, opts, opts.IdentityHeader || opts.DriftScores, obj, results)
+	}
+	return all
+}
+
+// loadResourceCache reads the resources written by saveResourceCache, which is empty if the file doesn't exist yet.
+func loadResourceCache(path string) (map[schema.GroupKind]schema.GroupVersionResource, error) {
+	out := make(map[schema.GroupKind]schema.GroupVersionResource)
+	var cache map[string]string
+	err := loadYaml(path, &cache)
+	if errors.Is(err, os.ErrNotExist) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't load the resource cache")
+	}
+	for gk, r := range cache {
+		gvr, _ := schema.ParseResourceArg(r)
+		if gvr == nil {
+			return nil, fmt.Errorf("invalid resource %q of %s in the resource cache", r, gk)
+		}
+		out[schema.ParseGroupKind(gk)] = *gvr
+	}
+	return out, nil
+}
+
+// saveResourceCache writes the resources keyed by the kinds like "Deployment.apps" as "deployments.v1.apps".
+func saveResourceCache(path string, resources map[schema.GroupKind]schema.GroupVersionResource) error {
+	cache := make(map[string]string, len(resources))
+	for gk, gvr := range resources {
+		cache[gk.String()] = strings.Join([]string{gvr.Resource, gvr.Version, gvr.Group}, ".")
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.WriteFile(path, data, 0o644))
+}
+
+// truncatedWidth is the width of the lines of diffs without --inline-values.
+const truncatedWidth = 120
+
+// shapeDiffs shortens the diffs of the results according to --diff-context and --inline-values,
+// and sorts the results with --sort-output and --sort-by-drift.
+func shapeDiffs(opts *Options, results []objdiff.DiffResult) {
+	if opts.SortOutput {
+		objdiff.SortResults(results)
+	}
+	if opts.SortByDrift {
+		objdiff.SortByDrift(results)
+	}
+	for i := range results {
+		diff := objdiff.ElideUnchanged(results[i].Diff, opts.DiffContext)
+		if !opts.InlineValues {
+			diff = objdiff.TruncateValues(diff, truncatedWidth)
+		}
+		results[i].Diff = diff
+	}
+}
+
+// writeReport writes the results to --out in --format.
+// The format is inferred from the extension of the path if it's empty.
+func writeReport(opts *Options, results []objdiff.DiffResult) error {
+	format := reportFormat(opts)
+	var data []byte
+	var err error
+	if format == report.FormatScript {
+		data, err = report.RenderScript(results, opts.ScriptDelete)
+	} else {
+		data, err = report.Render(format, opts.Formatter, results)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.WriteFile(opts.Out, data, 0o644))
+}
+
+// reportFormat returns the format of the report of --out.
+func reportFormat(opts *Options) report.Format {
+	if opts.Format == "" {
+		return report.FormatFromPath(opts.Out)
+	}
+	return opts.Format
+}
+
+// writesKubectlDiff tells if the report is in the format of kubectl diff, which needs the remote objects.
+func writesKubectlDiff(opts *Options) bool {
+	return opts.Out != "" && reportFormat(opts) == report.FormatKubectl
+}
+
+var (
+	success = color.New(color.FgGreen)
+	warn    = color.New(color.FgYellow)
+	fail    = color.New(color.FgRed)
+	bold    = color.New(color.Bold)
+)
+
+// printResultsFor prints the results of the local object as the verdicts with --verdicts
+// or with --template if it's given, otherwise as diffs.
+func printResultsFor(w io.Writer, opts *Options, header bool, obj *objdiff.Object, results []objdiff.DiffResult) {
+	if opts.Verdicts {
+		local := []*objdiff.Object{obj}
+		if obj.IsList() {
+			local = obj.Items
+		}
+		fmt.Fprint(w, report.RenderVerdicts(report.Verdicts(local, results)))
+		return
+	}
+	if opts.Template == nil {
+		printResults(w, opts.Formatter, header, opts.IncludeRemote, results)
+		return
+	}
+	out, err := report.RenderTemplate(opts.Template, results)
+	if err != nil {
+		warn.Fprintf(os.Stderr, "%+v\n", err.Error())
+	}
+	fmt.Fprint(w, out)
+}
+
+// printResults prints the results of a target.
+// Diffs are headed by the objects if header is true, which is needed when there may be multiple diffs,
+// and followed by the remote objects if remote is true.
+func printResults(w io.Writer, f *objdiff.Formatter, header, remote bool, results []objdiff.DiffResult) {
+	if len(results) == 0 {
+		success.Fprintf(w, "No diff.\n\n")
+		return
+	}
+	var presences, diffs []string
+	for _, r := range results {
+		var remoteYAML string
+		if remote {
+			remoteYAML = report.RemoteYAML(r)
+		}
+		switch {
+		case r.Class != objdiff.ClassChanged:
+			presences = append(presences, f.Format(r))
+		case header:
+			diffs = append(diffs, f.Format(r)+remoteYAML)
+		default:
+			diffs = append(diffs, r.Diff+remoteYAML)
+		}
+	}
+	if len(presences) != 0 {
+		fail.Fprintf(w, "%s\n", strings.Join(presences, ""))
+	}
+	if len(diffs) != 0 {
+		fail.Fprintf(w, "%s\n", strings.Join(diffs, "\n"))
+	}
+}
+
+// printForbidden prints the summary of the kinds which couldn't be compared due to the lack of permissions.
+func printForbidden(results []objdiff.DiffResult) {
+	seen := make(map[string]bool)
+	var kinds []string
+	for _, r := range results {
+		if r.Class != objdiff.ClassForbidden {
+			continue
+		}
+		if gk := r.Object.GroupVersionKind().GroupKind().String(); !seen[gk] {
+			seen[gk] = true
+			kinds = append(kinds, gk)
+		}
+	}
+	if len(kinds) != 0 {
+		sort.Strings(kinds)
+		warn.Fprintf(os.Stderr, "access denied to %s\n", strings.Join(kinds, ", "))
+	}
+}
+
+// needsHeader tells whether the diffs are prefixed with the objects.
+// Lists and replicas always need it to tell which object differs, while a single object has it only on demand
+// or to show the drift score.
+func needsHeader(opts *Options, target *Target, obj *objdiff.Object) bool {
+	return opts.IdentityHeader || opts.DriftScores || obj.IsList() || target.ReplicaSelector != ""
+}
+
+// watchTargets prints the results of the targets every time they change until it's interrupted.
+func watchTargets(opts *Options, targets []*Target, objs map[*Target]*objdiff.Object, serverVersion *util.Version, d *objdiff.Diff) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var mu sync.Mutex
+	errCh := make(chan error, len(targets))
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		obj, ok := objs[target]
+		if !ok {
+			continue
+		}
+		diffOpts, err := targetOptions(target, serverVersion)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		wg.Add(1)
+		go func(target *Target) {
+			defer wg.Done()
+			// the first results are already printed
+			first := true
+			err := d.Watch(ctx, target.APIVersion, target.Kind, obj, func(results []objdiff.DiffResult) error {
+				if first {
+					first = false
+					return nil
+				}
+				shapeDiffs(opts, results)
+				mu.Lock()
+				defer mu.Unlock()
+				bold.Printf("# %s (%s)\n", filepath.Base(target.Manifest), time.Now().Format(time.RFC3339))
+				printResultsFor(color.Output, opts, needsHeader(opts, target, obj), obj, results)
+				return nil
+			}, diffOpts...)
+			if err != nil {
+				errCh <- errors.Wrapf(err, "failed to watch %s", target.Manifest)
+				stop()
+			}
+		}(target)
+	}
+	wg.Wait()
+	close(errCh)
+	return <-errCh
 }
 
 func Run() error {
@@ -172,26 +1250,29 @@ func Run() error {
 		return errors.WithStack(err)
 	}
 
-	// set color
-	success := color.New(color.FgGreen)
-	warn := color.New(color.FgYellow)
-	fail := color.New(color.FgRed)
-	bold := color.New(color.Bold)
-
 	// read targetList.yaml
 	var targets []*Target
-	err = loadYaml(opts.Target, &targets)
-	if err != nil {
-		return errors.WithStack(err)
+	if opts.Target != "" {
+		err = loadYaml(opts.Target, &targets)
+		if err != nil {
+			return errors.WithStack(err)
+		}
 	}
 
-	config, err := clientcmd.BuildConfigFromFlags("", opts.Kubeconfig)
+	if opts.Baseline != "" {
+		return diffSets(opts)
+	}
+
+	config, err := objdiff.LoadConfig(opts.Kubeconfig, opts.ContextB)
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	if opts.As != "" {
+		config.Impersonate = rest.ImpersonationConfig{UserName: opts.As, Groups: opts.AsGroups}
+	}
 
 	version := opts.Version
-	if opts.Version == nil {
+	if opts.Version == nil && opts.Since == "" && opts.Render == "" && opts.HelmRelease == "" && len(opts.Inventory) == 0 {
 		client, err := configv1.NewForConfig(config)
 		if err != nil {
 			return errors.WithStack(err)
@@ -206,30 +1287,333 @@ func Run() error {
 		}
 	}
 
-	d, err := objdiff.New(config)
+	if opts.Hash {
+		return printHashes(os.Stdout, opts, targets, version)
+	}
+
+	diffOpts := []objdiff.Option{objdiff.WithPerspective(opts.Perspective)}
+	if !opts.ModifiedSince.IsZero() {
+		diffOpts = append(diffOpts, objdiff.WithModifiedSince(opts.ModifiedSince, opts.ModifiedSinceAnnotation))
+	}
+	if opts.PartialFetch {
+		diffOpts = append(diffOpts, objdiff.WithPartialFetch())
+	}
+	if opts.Protobuf {
+		diffOpts = append(diffOpts, objdiff.WithProtobuf())
+	}
+	if opts.PageSize > 0 {
+		diffOpts = append(diffOpts, objdiff.WithPageSize(opts.PageSize))
+	}
+	if opts.NoConcurrency {
+		diffOpts = append(diffOpts, objdiff.WithSerial())
+	}
+	if opts.InformerCache {
+		diffOpts = append(diffOpts, objdiff.WithInformerCache(opts.InformerResync))
+	}
+	if opts.StructuredMerge {
+		diffOpts = append(diffOpts, objdiff.WithStructuredMerge())
+	}
+	if opts.KeepManagedFields {
+		diffOpts = append(diffOpts, objdiff.WithManagedFields())
+	}
+	if opts.FieldManager != "" {
+		diffOpts = append(diffOpts, objdiff.WithFieldManager(opts.FieldManager))
+	}
+	if len(opts.Fields) != 0 {
+		diffOpts = append(diffOpts, objdiff.WithFields(opts.Fields...))
+	}
+	if opts.CheckGeneration {
+		diffOpts = append(diffOpts, objdiff.WithGenerationCheck())
+	}
+	if opts.DataSummary {
+		diffOpts = append(diffOpts, objdiff.WithDataSummary())
+	}
+	if opts.MaxDepth > 0 {
+		diffOpts = append(diffOpts, objdiff.WithMaxDepth(opts.MaxDepth))
+	}
+	if opts.ExplainDiff {
+		diffOpts = append(diffOpts, objdiff.WithExplanations())
+	}
+	if opts.DryRunCreate {
+		diffOpts = append(diffOpts, objdiff.WithDryRunCreate())
+	}
+	if opts.IgnoreGenerated {
+		diffOpts = append(diffOpts, objdiff.WithoutGeneratedFields())
+	}
+	if opts.IgnoreOwnerUIDs {
+		diffOpts = append(diffOpts, objdiff.WithoutOwnerUIDs())
+	}
+	if opts.IncludeRemote || writesKubectlDiff(opts) {
+		diffOpts = append(diffOpts, objdiff.WithRemoteObjects())
+	}
+	if opts.DriftScores {
+		diffOpts = append(diffOpts, objdiff.WithDriftScores())
+	}
+	if opts.CleanObjects {
+		diffOpts = append(diffOpts, objdiff.WithCleanedObjects())
+	}
+	if !opts.AllNamespaces {
+		diffOpts = append(diffOpts, objdiff.WithItemNamespaces())
+	}
+	diffOpts = append(diffOpts, objdiff.WithIgnoredAnnotations(opts.IgnoredAnnotations...))
+	diffOpts = append(diffOpts, objdiff.WithIgnoredAnnotationPrefixes(opts.IgnoredAnnotationPrefixes...))
+	if opts.IntersectionOnly {
+		diffOpts = append(diffOpts, objdiff.WithIntersectionOnly())
+	}
+	if opts.NamePattern != nil {
+		diffOpts = append(diffOpts, objdiff.WithNamePattern(opts.NamePattern))
+	}
+	if opts.Transform != nil {
+		diffOpts = append(diffOpts, objdiff.WithTransform(opts.Transform))
+	}
+	if len(opts.ExcludedNamespaces) != 0 {
+		diffOpts = append(diffOpts, objdiff.WithExcludedNamespaces(opts.ExcludedNamespaces...))
+	}
+	for gk, v := range opts.FetchVersions {
+		diffOpts = append(diffOpts, objdiff.WithFetchVersion(gk, v))
+	}
+	for gk, s := range opts.Subresources {
+		diffOpts = append(diffOpts, objdiff.WithSubresource(gk, s))
+	}
+	if opts.PinVersions != nil {
+		diffOpts = append(diffOpts, objdiff.WithPinnedVersions(opts.PinVersions))
+	}
+	if opts.RevealSecrets {
+		diffOpts = append(diffOpts, objdiff.WithRevealedSecrets())
+	}
+	if opts.ResourceCache != "" {
+		resources, err := loadResourceCache(opts.ResourceCache)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		diffOpts = append(diffOpts, objdiff.WithResolvedResources(resources))
+	}
+	diffOpts = append(diffOpts, objdiff.WithSensitiveKinds(opts.SensitiveKinds...), objdiff.WithSensitivePaths(opts.SensitivePaths...))
+	d, err := objdiff.New(config, diffOpts...)
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	var a *objdiff.Diff
+	if opts.ContextA != "" {
+		if opts.As != "" {
+			diffOpts = append(diffOpts, objdiff.WithImpersonation(opts.As, opts.AsGroups...))
+		}
+		a, err = objdiff.NewFromKubeconfig(opts.Kubeconfig, opts.ContextA, diffOpts...)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if len(opts.Inventory) != 0 {
+		return printInventory(os.Stdout, opts.Inventory, opts.InventoryStubs, d)
+	}
 
 	for _, target := range targets {
-		bold.Printf("# %s\n", filepath.Base(target.Manifest))
+		if target.Resource == "" || target.APIVersion != "" || target.Kind != "" {
+			continue
+		}
+		gvk, err := d.ResolveResource(target.Resource)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't resolve the resource of %s", target.Manifest)
+		}
+		target.APIVersion, target.Kind = gvk.ToAPIVersionAndKind()
+	}
+
+	local := "manifest"
+	if opts.Since != "" {
+		local = "snapshot " + opts.Since
+	}
+	if opts.Render != "" {
+		local = "rendered " + opts.RenderPath
+		if opts.GitRepo != "" {
+			local += "@" + opts.GitRef
+		}
+	}
+	if opts.HelmRelease != "" {
+		local = "helm release " + opts.HelmNamespace + "/" + opts.HelmRelease
+	}
+	serverVersion, err := d.ServerVersion()
+	if err != nil {
+		return errors.WithStack(err)
+	}
 
-		presences, diffs, err := checkTarget(opts, target, version, d)
+	remote := "cluster"
+	if opts.ContextA != "" {
+		local = "context " + opts.ContextA
+	}
+	if opts.CompareVersion != "" {
+		local, remote = "cluster at the versions of the manifests", "cluster at "+opts.CompareVersion
+	}
+	if opts.ContextB != "" {
+		remote = "context " + opts.ContextB
+	}
+	minus, plus := opts.Perspective.Labels(local, remote)
+	bold.Printf("--- %s\n+++ %s\n\n", minus, plus)
+
+	var all []objdiff.DiffResult
+	if opts.Render != "" {
+		all, err = diffRendered(opts, d)
 		if err != nil {
-			warn.Fprintf(os.Stderr, "skipped due to error: %+v\n", err.Error())
+			return errors.WithStack(err)
+		}
+	}
+	if opts.HelmRelease != "" {
+		results, err := diffHelmRelease(opts, d)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		all = append(all, results...)
+	}
+	objs := make(map[*Target]*objdiff.Object)
+	slots := make([]targetResult, len(targets))
+	w := newOrderedWriter(color.Output)
+	sem := make(chan struct{}, opts.Concurrency)
+	var progress *progressReporter
+	if opts.Progress {
+		progress = startProgress(os.Stderr, os.Stderr.Fd(), d, len(targets))
+	}
+	compare := func(i int, target *Target) {
+		var buf bytes.Buffer
+		slots[i] = diffTarget(&buf, opts, target, version, serverVersion, a, d)
+		if progress != nil {
+			progress.Done()
+		}
+		if err := w.Done(i, &buf); err != nil {
+			warn.Fprintf(os.Stderr, "couldn't write the results: %+v\n", err.Error())
+		}
+	}
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		if opts.NoConcurrency {
+			// in the current goroutine for deterministic debugging, which writes the same output
+			compare(i, target)
 			continue
 		}
-
-		if len(presences) == 0 && len(diffs) == 0 {
-			success.Printf("No diff.\n\n")
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, target *Target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			compare(i, target)
+		}(i, target)
+	}
+	wg.Wait()
+	if progress != nil {
+		progress.Stop()
+	}
+	for i, target := range targets {
+		if slots[i].obj == nil {
 			continue
 		}
-		if len(presences) != 0 {
-			fail.Printf("%s\n", strings.Join(presences, ""))
+		all = append(all, slots[i].results...)
+		objs[target] = slots[i].obj
+	}
+
+	printForbidden(all)
+
+	if opts.SortOutput {
+		objdiff.SortResults(all)
+	}
+	if opts.SortByDrift {
+		objdiff.SortByDrift(all)
+	}
+	if opts.Out != "" {
+		err = writeReport(opts, all)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if opts.ResourceCache != "" {
+		err = saveResourceCache(opts.ResourceCache, d.ResolvedResources())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if opts.RecordVersions != "" {
+		data, err := json.Marshal(d.ObservedVersions())
+		if err != nil {
+			return errors.WithStack(err)
 		}
-		if len(diffs) != 0 {
-			fail.Printf("%s\n", strings.Join(diffs, "\n"))
+		err = os.WriteFile(opts.RecordVersions, data, 0o644)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if opts.Watch {
+		return watchTargets(opts, targets, objs, serverVersion, d)
+	}
+
+	for _, r := range all {
+		if opts.FailOn[r.Class] {
+			return ErrDiffFound
 		}
 	}
 	return nil
 }
+
+// printOptionEffects prints the options applied to the comparison of the objects with the cluster
+// and the paths each of them hides. Objects missing from the cluster only have the options printed.
+func printOptionEffects(w io.Writer, target *Target, obj *objdiff.Object, serverVersion *util.Version, d *objdiff.Diff) {
+	versioned, err := targetVersionedOptions(target)
+	if err != nil {
+		warn.Fprintf(os.Stderr, "couldn't explain the options: %+v\n", err.Error())
+		return
+	}
+	named := objdiff.NamedOptionsFor(serverVersion, versioned...)
+	objs := []*objdiff.Object{obj}
+	if obj.IsList() {
+		objs = obj.Items
+	}
+	for _, o := range objs {
+		fmt.Fprintf(w, "# options of %s\n", o)
+		remote, err := d.Fetch(o.APIVersion, o.Kind, o)
+		if err != nil {
+			if !apierrors.IsNotFound(errors.Cause(err)) {
+				warn.Fprintf(os.Stderr, "couldn't fetch %s: %+v\n", o, err.Error())
+			}
+			for _, n := range d.AppliedOptions(o, named) {
+				fmt.Fprintf(w, "%s\n", n.Name)
+			}
+			continue
+		}
+		effects, err := d.OptionEffects(o, remote, named)
+		if err != nil {
+			warn.Fprintf(os.Stderr, "couldn't explain the options of %s: %+v\n", o, err.Error())
+			continue
+		}
+		for _, e := range effects {
+			fmt.Fprintf(w, "%s\n", e.Name)
+			for _, p := range e.Paths {
+				fmt.Fprintf(w, "  hides %s\n", p)
+			}
+		}
+	}
+}
+
+// printSchemaFindings prints the schema drift of the objects in the cluster.
+// Kinds without a CRD are skipped silently since built-in kinds have no schema to fetch.
+func printSchemaFindings(w io.Writer, target *Target, obj *objdiff.Object, d *objdiff.Diff) {
+	objs := []*objdiff.Object{obj}
+	if obj.IsList() {
+		objs = obj.Items
+	}
+	for _, o := range objs {
+		apiVersion, kind := target.APIVersion, target.Kind
+		if obj.IsList() {
+			apiVersion, kind = o.APIVersion, o.Kind
+		}
+		findings, err := d.CheckSchema(apiVersion, kind, o)
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				warn.Fprintf(os.Stderr, "couldn't check the schema of %s: %+v\n", o, err.Error())
+			}
+			continue
+		}
+		for _, f := range findings {
+			warn.Fprintf(w, "! %s %s\n", o, f)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"github.com/cockroachdb/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+	"github.com/bitoku/difftool/pkg/util"
+)
+
+// checkContexts compares the counterpart of the manifest in the context A with the cluster,
+// where the manifest only tells which objects to compare.
+// The identities of the objects of the context A are remapped before they're paired with the cluster,
+// and the ones still unpaired are reported as missing.
+// An object which doesn't exist in the context A is extra if its remapped one exists in the cluster.
+func checkContexts(opts *Options, target *Target, obj *objdiff.Object, serverVersion *util.Version, a, d *objdiff.Diff) ([]objdiff.DiffResult, error) {
+	remap := objdiff.ChainRemaps(objdiff.RemapNamespaces(opts.RemapNamespaces), objdiff.RemapSuffixes(opts.RemapSuffixes))
+	fetched, err := a.Fetch(target.APIVersion, target.Kind, obj)
+	if err == nil {
+		return checkTarget(target, objdiff.Remap(fetched, remap), serverVersion, d)
+	}
+	if !apierrors.IsNotFound(errors.Cause(err)) {
+		return nil, errors.Wrapf(err, "couldn't fetch it from the context %s", opts.ContextA)
+	}
+	remote, err := d.Fetch(target.APIVersion, target.Kind, objdiff.Remap(obj, remap))
+	if apierrors.IsNotFound(errors.Cause(err)) {
+		return []objdiff.DiffResult{}, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return []objdiff.DiffResult{{Class: objdiff.ClassExtra, Object: remote, Perspective: opts.Perspective, ResourceVersion: remote.ResourceVersion}}, nil
+}
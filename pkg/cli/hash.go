@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+	"github.com/bitoku/difftool/pkg/util"
+)
+
+// printHashes prints the hashes of the comparable content of the objects in the manifests of the targets
+// like sha256sum, every item of lists separately.
+func printHashes(w io.Writer, opts *Options, targets []*Target, version *util.Version) error {
+	for _, target := range targets {
+		obj, err := loadManifest(opts, target, version)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't load %s", target.Manifest)
+		}
+		objs := []*objdiff.Object{obj}
+		if obj.IsList() {
+			objs = obj.Items
+		}
+		for _, o := range objs {
+			// Hash doesn't tell why the content can't be hashed
+			if _, err := objdiff.Canonicalize(o); err != nil {
+				return errors.WithStack(err)
+			}
+			fmt.Fprintf(w, "%s  %s\n", objdiff.Hash(o), o)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cockroachdb/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+)
+
+// inventoryStub is the identity of an object in the cluster written as a manifest to be filled in.
+type inventoryStub struct {
+	v1.TypeMeta `json:",inline"`
+	Metadata    struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace,omitempty"`
+	} `json:"metadata"`
+}
+
+// printInventory prints the identities of the objects in the cluster of the resources,
+// or yaml stubs of them if stubs is true.
+func printInventory(w io.Writer, resources []string, stubs bool, d *objdiff.Diff) error {
+	for _, r := range resources {
+		objs, err := d.Inventory(r)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't list %s", r)
+		}
+		for _, o := range objs {
+			if !stubs {
+				fmt.Fprintln(w, o)
+				continue
+			}
+			stub := inventoryStub{TypeMeta: o.TypeMeta}
+			stub.Metadata.Name, stub.Metadata.Namespace = o.Name, o.Namespace
+			data, err := yaml.Marshal(stub)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			fmt.Fprintf(w, "---\n%s", data)
+		}
+	}
+	return nil
+}
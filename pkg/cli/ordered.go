@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// orderedWriter writes the outputs buffered in slots in the order of the slots as they complete,
+// so that concurrent diffs are printed as if they ran one by one.
+// The slots are the targets in the order of the target list rather than the identities of the objects,
+// which are only known once the manifests are loaded, so that each output is flushed as soon as the ones before it are.
+// --sort-output sorts the results of the report by the identities instead.
+type orderedWriter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	next    int
+	pending map[int]*bytes.Buffer
+}
+
+func newOrderedWriter(out io.Writer) *orderedWriter {
+	return &orderedWriter{out: out, pending: make(map[int]*bytes.Buffer)}
+}
+
+// Done completes the slot with its output, and flushes it along with the following slots completed
+// once every slot before it is flushed. A slot is written at once, so its output is never interleaved.
+func (w *orderedWriter) Done(slot int, buf *bytes.Buffer) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending[slot] = buf
+	for {
+		b, ok := w.pending[w.next]
+		if !ok {
+			return nil
+		}
+		delete(w.pending, w.next)
+		w.next++
+		if _, err := w.out.Write(b.Bytes()); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattn/go-isatty"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+)
+
+// progressReporter prints the progress of the diffs to stderr so that stdout stays machine-readable.
+// It rewrites a single line on a terminal, and prints a line periodically otherwise for CI logs.
+type progressReporter struct {
+	out   io.Writer
+	tty   bool
+	d     *objdiff.Diff
+	total int
+	done  atomic.Int64
+	stop  chan struct{}
+	ended chan struct{}
+}
+
+func startProgress(out io.Writer, fd uintptr, d *objdiff.Diff, total int) *progressReporter {
+	p := &progressReporter{
+		out:   out,
+		tty:   isatty.IsTerminal(fd),
+		d:     d,
+		total: total,
+		stop:  make(chan struct{}),
+		ended: make(chan struct{}),
+	}
+	interval := 10 * time.Second
+	if p.tty {
+		interval = 200 * time.Millisecond
+	}
+	go func() {
+		defer close(p.ended)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.print()
+			case <-p.stop:
+				p.print()
+				if p.tty {
+					fmt.Fprintln(p.out)
+				}
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// Done counts a target as compared.
+func (p *progressReporter) Done() {
+	p.done.Add(1)
+}
+
+// Stop prints the last progress and stops reporting.
+func (p *progressReporter) Stop() {
+	close(p.stop)
+	<-p.ended
+}
+
+func (p *progressReporter) print() {
+	progress := p.d.Progress()
+	line := fmt.Sprintf("targets %d/%d, fetched %d, diffed %d", p.done.Load(), p.total, progress.Fetched, progress.Diffed)
+	if p.tty {
+		fmt.Fprintf(p.out, "\r\033[K%s", line)
+		return
+	}
+	fmt.Fprintln(p.out, line)
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/fatih/color"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+	"github.com/bitoku/difftool/pkg/source"
+)
+
+// diffSets compares the manifests in the directory of the argument with the ones in --baseline without the cluster,
+// pairing the objects by their identities.
+func diffSets(opts *Options) error {
+	dir := opts.Compared
+	baseline, err := source.LoadDir(opts.Baseline)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't load %s", opts.Baseline)
+	}
+	other, err := source.LoadDir(dir)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't load %s", dir)
+	}
+	f, err := objdiff.NewFormatter("{{.Sign}} {{.Object}} exists only in the baseline", "{{.Sign}} {{.Object}} doesn't exist in the baseline")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	bold.Printf("--- %s\n+++ %s\n\n", opts.Baseline, dir)
+	results, err := objdiff.DiffList(baseline, other)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	shapeDiffs(opts, results)
+	printResults(color.Output, f, true, false, results)
+
+	if opts.Out != "" {
+		if err := writeReport(opts, results); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, r := range results {
+		if opts.FailOn[r.Class] {
+			return ErrDiffFound
+		}
+	}
+	return nil
+}
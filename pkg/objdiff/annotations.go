@@ -0,0 +1,97 @@
+package objdiff
+
+import (
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/strings/slices"
+)
+
+// DefaultIgnoredAnnotations is the bookkeeping annotations of rollouts, which change on every rollout or restart
+// like `kubectl rollout restart` rather than drift.
+var DefaultIgnoredAnnotations = []string{
+	"kubectl.kubernetes.io/restartedAt",
+	"deployment.kubernetes.io/revision",
+	"deprecated.daemonset.template.generation",
+	"kubernetes.io/change-cause",
+}
+
+// DefaultIgnoredAnnotationPrefixes is the prefixes of the annotations tools set for their own bookkeeping
+// like the last applied configuration of kubectl or the release of helm.
+var DefaultIgnoredAnnotationPrefixes = []string{
+	"kubectl.kubernetes.io/",
+	"meta.helm.sh/",
+	"kustomize.config.k8s.io/",
+	"argocd.argoproj.io/",
+	// the annotations of difftool itself like SkipAnnotation
+	"difftool/",
+}
+
+// WithIgnoredAnnotations replaces DefaultIgnoredAnnotations ignored by IgnoreAnnotations in every comparison.
+// No annotations are ignored if it's empty.
+func WithIgnoredAnnotations(keys ...string) Option {
+	return func(d *Diff) {
+		d.ignoredAnnotations = keys
+	}
+}
+
+// WithIgnoredAnnotationPrefixes replaces DefaultIgnoredAnnotationPrefixes ignored by IgnoreAnnotationPrefixes
+// in every comparison. No annotations are ignored by their prefixes if it's empty.
+func WithIgnoredAnnotationPrefixes(prefixes ...string) Option {
+	return func(d *Diff) {
+		d.ignoredAnnotationPrefixes = prefixes
+	}
+}
+
+// IgnoreAnnotations ignores the annotations of the keys wherever they are, like in the pod template of a Deployment.
+func IgnoreAnnotations(keys ...string) cmp.Option {
+	return ignoreAnnotationsBy(func(key string) bool {
+		return slices.Contains(keys, key)
+	})
+}
+
+// IgnoreAnnotationPrefixes ignores the annotations whose keys start with any of the prefixes wherever they are.
+func IgnoreAnnotationPrefixes(prefixes ...string) cmp.Option {
+	return ignoreAnnotationsBy(func(key string) bool {
+		for _, p := range prefixes {
+			if strings.HasPrefix(key, p) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ignoreAnnotationsBy ignores the annotations whose keys match.
+func ignoreAnnotationsBy(match func(key string) bool) cmp.Option {
+	return cmp.FilterPath(func(path cmp.Path) bool {
+		if len(path) < 2 {
+			return false
+		}
+		key, ok := path.Last().(cmp.MapIndex)
+		if !ok || !match(key.Key().String()) {
+			return false
+		}
+		// the parent is the map of the annotations, which may be behind a type assertion of any
+		for i := len(path) - 2; i >= 0; i-- {
+			switch s := path.Index(i).(type) {
+			case cmp.TypeAssertion:
+				continue
+			case cmp.MapIndex:
+				return s.Key().String() == "annotations"
+			}
+			return false
+		}
+		return false
+	}, cmp.Ignore())
+}
+
+// withDefaultOptions adds the options every comparison of the local object by the Diff takes to opts.
+func (d *Diff) withDefaultOptions(local *Object, opts []cmp.Option) []cmp.Option {
+	// opts isn't appended to since it may be shared by concurrent diffs
+	defaults := []cmp.Option{cmp.Options(opts)}
+	for _, o := range d.defaultOptions(local) {
+		defaults = append(defaults, o.Option)
+	}
+	return defaults
+}
@@ -0,0 +1,106 @@
+package objdiff
+
+import (
+	"github.com/cockroachdb/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// WithResolvedResources seeds the resources resolved by an earlier run like ResolvedResources returns,
+// so that the RESTMapper isn't asked again for the kinds already known.
+// The seeded resources are checked against the discovery of their group version on the first use,
+// since they may have been removed or renamed since the earlier run.
+func WithResolvedResources(resources map[schema.GroupKind]schema.GroupVersionResource) Option {
+	return func(d *Diff) {
+		for gk, gvr := range resources {
+			d.resolved[gk] = gvr
+			d.seeded[gk] = true
+		}
+	}
+}
+
+// ResolvedResources returns the resources resolved so far keyed by the kinds of the local objects.
+func (d *Diff) ResolvedResources() map[schema.GroupKind]schema.GroupVersionResource {
+	d.resolvedMu.Lock()
+	defer d.resolvedMu.Unlock()
+	out := make(map[schema.GroupKind]schema.GroupVersionResource, len(d.resolved))
+	for gk, gvr := range d.resolved {
+		out[gk] = gvr
+	}
+	return out
+}
+
+// cachedMapping returns the mapping of the resolved resource if it serves the kind.
+// The served kind is the given one if the version matches, or the converter's target kind otherwise.
+func (d *Diff) cachedMapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, bool) {
+	d.resolvedMu.Lock()
+	gvr, ok := d.resolved[gvk.GroupKind()]
+	seeded := d.seeded[gvk.GroupKind()]
+	d.resolvedMu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	if seeded && !d.verifySeeded(gvk.GroupKind(), gvr) {
+		return nil, false
+	}
+	if gvr.GroupVersion() == gvk.GroupVersion() {
+		return &meta.RESTMapping{Resource: gvr, GroupVersionKind: gvk}, true
+	}
+	if c, ok := d.conversions[gvk.GroupKind()]; ok && c.to.Group == gvr.Group {
+		return &meta.RESTMapping{Resource: gvr, GroupVersionKind: c.to.WithVersion(gvr.Version)}, true
+	}
+	return nil, false
+}
+
+// verifySeeded tells if the seeded resource of the kind is still served, and forgets it otherwise
+// so that it's resolved again. The resources of a group version are discovered once per run.
+func (d *Diff) verifySeeded(gk schema.GroupKind, gvr schema.GroupVersionResource) bool {
+	served, err := d.servedResources(gvr.GroupVersion())
+	d.resolvedMu.Lock()
+	defer d.resolvedMu.Unlock()
+	delete(d.seeded, gk)
+	if err != nil || !served[gvr.Resource] {
+		delete(d.resolved, gk)
+		return false
+	}
+	return true
+}
+
+// servedResources returns the names of the resources served in the group version.
+func (d *Diff) servedResources(gv schema.GroupVersion) (map[string]bool, error) {
+	d.resolvedMu.Lock()
+	served, ok := d.served[gv]
+	d.resolvedMu.Unlock()
+	if ok {
+		return served, nil
+	}
+	list, err := d.discovery.ServerResourcesForGroupVersion(gv.String())
+	if err != nil && !kerrors.IsNotFound(err) {
+		return nil, errors.WithStack(err)
+	}
+	served = make(map[string]bool)
+	if list != nil {
+		for _, r := range list.APIResources {
+			served[r.Name] = true
+		}
+	}
+	d.resolvedMu.Lock()
+	defer d.resolvedMu.Unlock()
+	d.served[gv] = served
+	return served, nil
+}
+
+func (d *Diff) cacheMapping(gk schema.GroupKind, mapping *meta.RESTMapping) {
+	d.resolvedMu.Lock()
+	defer d.resolvedMu.Unlock()
+	d.resolved[gk] = mapping.Resource
+	delete(d.seeded, gk)
+}
+
+// forgetMapping drops the resource of the kind which isn't served anymore.
+func (d *Diff) forgetMapping(gk schema.GroupKind) {
+	d.resolvedMu.Lock()
+	defer d.resolvedMu.Unlock()
+	delete(d.resolved, gk)
+}
@@ -0,0 +1,46 @@
+package objdiff
+
+import (
+	"testing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestCachedMappingVerifiesSeeded(t *testing.T) {
+	discovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*v1.APIResourceList{
+		{GroupVersion: "example.com/v1", APIResources: []v1.APIResource{{Name: "widgets", Kind: "Widget"}}},
+	}}}
+	widget := schema.GroupKind{Group: "example.com", Kind: "Widget"}
+	gadget := schema.GroupKind{Group: "example.com", Kind: "Gadget"}
+	tests := []struct {
+		name   string
+		gk     schema.GroupKind
+		gvr    schema.GroupVersionResource
+		cached bool
+	}{
+		{name: "served", gk: widget, gvr: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}, cached: true},
+		{name: "renamed", gk: gadget, gvr: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "gadgets"}},
+		{name: "version removed", gk: widget, gvr: schema.GroupVersionResource{Group: "example.com", Version: "v1beta1", Resource: "widgets"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Diff{
+				discovery: discovery,
+				resolved:  make(map[schema.GroupKind]schema.GroupVersionResource),
+				seeded:    make(map[schema.GroupKind]bool),
+				served:    make(map[schema.GroupVersion]map[string]bool),
+			}
+			WithResolvedResources(map[schema.GroupKind]schema.GroupVersionResource{tt.gk: tt.gvr})(d)
+			_, ok := d.cachedMapping(tt.gk.WithVersion(tt.gvr.Version))
+			if ok != tt.cached {
+				t.Errorf("cached = %v, want %v", ok, tt.cached)
+			}
+			if _, ok := d.ResolvedResources()[tt.gk]; ok != tt.cached {
+				t.Errorf("kept = %v, want %v", ok, tt.cached)
+			}
+		})
+	}
+}
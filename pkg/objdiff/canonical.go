@@ -0,0 +1,71 @@
+package objdiff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Canonicalize returns the payload of the object compared by the extractor of its kind as deterministic json,
+// where maps are sorted by their keys and integral numbers are written the same whether they're decoded as int64 or float64,
+// so that the same content always has the same bytes.
+func Canonicalize(obj *Object) ([]byte, error) {
+	payload, err := getExtractor(obj.GroupVersionKind().GroupKind())(obj)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't extract %s", obj)
+	}
+	b, err := json.Marshal(canonicalValue(payload))
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't canonicalize %s", obj)
+	}
+	return b, nil
+}
+
+// Hash returns the sha256 of Canonicalize in hex, which tells if the meaningful content of the object has changed
+// without comparing it. It's empty if the payload can't be extracted.
+func Hash(obj *Object) string {
+	b, err := Canonicalize(obj)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalValue normalizes the numbers in v, while json.Marshal sorts the keys of maps.
+func canonicalValue(v any) any {
+	switch x := v.(type) {
+	case float64:
+		// integers beyond 2^53 can't be told from their neighbors as float64 anyway
+		if x == math.Trunc(x) && math.Abs(x) < 1<<53 {
+			return int64(x)
+		}
+		return x
+	case int:
+		return int64(x)
+	case []any:
+		out := make([]any, len(x))
+		for i, e := range x {
+			out[i] = canonicalValue(e)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, e := range x {
+			out[k] = canonicalValue(e)
+		}
+		return out
+	case DataMap:
+		out := make(map[string]any, len(x))
+		for k, e := range x {
+			out[k] = canonicalValue(e)
+		}
+		return out
+	case ConflictingValue:
+		return ConflictingValue{Data: canonicalValue(x.Data), BinaryData: x.BinaryData}
+	}
+	return v
+}
@@ -0,0 +1,56 @@
+package objdiff
+
+import (
+	"github.com/cockroachdb/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CleanForDiff returns a copy of obj without the fields set by the server like `kubectl get --export` used to,
+// which are the resourceVersion, uid, creationTimestamp, generation, managedFields, selfLink and status.
+// Items of a list are cleaned as well.
+func CleanForDiff(obj *Object) *Object {
+	out := *obj
+	out.ResourceVersion = ""
+	out.UID = ""
+	out.CreationTimestamp = v1.Time{}
+	out.Generation = 0
+	out.ManagedFields = nil
+	out.SelfLink = ""
+	out.Status = nil
+	if obj.Items != nil {
+		out.Items = make([]*Object, len(obj.Items))
+		for i, item := range obj.Items {
+			out.Items[i] = CleanForDiff(item)
+		}
+	}
+	return &out
+}
+
+// WithCleanedObjects compares the objects cleaned by CleanForDiff, which matters to the extractors comparing the metadata.
+// The results still carry the objects as they are.
+func WithCleanedObjects() Option {
+	return func(d *Diff) {
+		d.cleanObjects = true
+	}
+}
+
+// comparable returns the copies of the objects which are actually compared,
+// which are cleaned, transformed and only have the fields owned by the field manager as configured.
+func (d *Diff) comparable(local, remote *Object) (*Object, *Object, error) {
+	local, remote, err := d.projectOwned(local, remote)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	if d.cleanObjects {
+		local, remote = CleanForDiff(local), CleanForDiff(remote)
+	}
+	if d.transform != nil {
+		if local, err = d.transform.Apply(local); err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		if remote, err = d.transform.Apply(remote); err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+	}
+	return local, remote, nil
+}
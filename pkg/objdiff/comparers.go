@@ -0,0 +1,40 @@
+package objdiff
+
+import (
+	"sync"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	comparersMu sync.RWMutex
+	comparers   = map[schema.GroupKind][]cmp.Option{}
+)
+
+// RegisterComparer associates the options like cmp.Comparer with the kind, which are applied to every comparison
+// of the objects of the kind in addition to the given options, like
+//
+//	RegisterComparer(schema.GroupKind{Group: "example.com", Kind: "Widget"}, cmp.Comparer(equalQuantities))
+//
+// Payloads decoded from json are maps, so comparers of Go types apply to the payloads of extractors returning them.
+// Ignoring options still win over the comparers since go-cmp evaluates them first,
+// while a comparer and another option applying to the same values is an error of go-cmp.
+// The options are appended to the registered ones of the kind.
+func RegisterComparer(gk schema.GroupKind, opts ...cmp.Option) {
+	comparersMu.Lock()
+	defer comparersMu.Unlock()
+	comparers[gk] = append(comparers[gk], opts...)
+}
+
+// withComparers adds the options registered for the kind of obj to opts.
+func withComparers(obj *Object, opts []cmp.Option) []cmp.Option {
+	comparersMu.RLock()
+	defer comparersMu.RUnlock()
+	registered := comparers[obj.GroupVersionKind().GroupKind()]
+	if len(registered) == 0 {
+		return opts
+	}
+	out := make([]cmp.Option, 0, len(opts)+len(registered))
+	return append(append(out, opts...), registered...)
+}
@@ -0,0 +1,101 @@
+package objdiff
+
+import (
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Converter converts a local object into the given group/version/kind,
+// which is the one served by the cluster.
+type Converter interface {
+	Convert(obj *Object, to schema.GroupVersionKind) (*Object, error)
+}
+
+// ConverterFunc is an adapter to use an ordinary function as a Converter.
+type ConverterFunc func(obj *Object, to schema.GroupVersionKind) (*Object, error)
+
+func (f ConverterFunc) Convert(obj *Object, to schema.GroupVersionKind) (*Object, error) {
+	return f(obj, to)
+}
+
+type conversion struct {
+	to        schema.GroupKind
+	converter Converter
+}
+
+// WithConverter registers a converter for objects of the `from` kind.
+// When the version of a local object is not served by the cluster, it is
+// converted into the preferred version of the `to` kind before diffing.
+// `from` and `to` may be the same kind when only the version has changed.
+func WithConverter(from, to schema.GroupKind, c Converter) Option {
+	return func(d *Diff) {
+		d.conversions[from] = conversion{to: to, converter: c}
+	}
+}
+
+// WithFetchVersion makes the remote objects of the kind be fetched at the version regardless of the local objects,
+// like the storage version to see the representation without conversion webhooks.
+func WithFetchVersion(gk schema.GroupKind, version string) Option {
+	return func(d *Diff) {
+		d.fetchVersions[gk] = version
+	}
+}
+
+// resolve returns the mapping used to fetch the remote objects of the given kind.
+// If the given version isn't served but a converter is registered, it falls back
+// to the preferred version of the converter's target kind.
+func (d *Diff) resolve(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	if v, ok := d.fetchVersions[gvk.GroupKind()]; ok {
+		mapping, err := d.mapper.RESTMapping(gvk.GroupKind(), v)
+		if meta.IsNoMatchError(err) {
+			return nil, errors.Newf("version %s of %s isn't served by the cluster", v, gvk.GroupKind())
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return mapping, nil
+	}
+	if mapping, ok := d.cachedMapping(gvk); ok {
+		return mapping, nil
+	}
+	mapping, err := d.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err == nil {
+		d.cacheMapping(gvk.GroupKind(), mapping)
+		return mapping, nil
+	}
+	if meta.IsNoMatchError(err) {
+		d.forgetMapping(gvk.GroupKind())
+	}
+	c, ok := d.conversions[gvk.GroupKind()]
+	if !meta.IsNoMatchError(err) || !ok {
+		return nil, errors.WithStack(err)
+	}
+	mapping, err = d.mapper.RESTMapping(c.to)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			d.forgetMapping(gvk.GroupKind())
+		}
+		return nil, errors.WithStack(err)
+	}
+	d.cacheMapping(gvk.GroupKind(), mapping)
+	return mapping, nil
+}
+
+// convert converts obj into the given kind if it differs and a converter is registered.
+// The object is returned as is otherwise.
+func (d *Diff) convert(obj *Object, to schema.GroupVersionKind) (*Object, error) {
+	from := obj.GroupVersionKind()
+	if from == to {
+		return obj, nil
+	}
+	c, ok := d.conversions[from.GroupKind()]
+	if !ok || c.to != to.GroupKind() {
+		return obj, nil
+	}
+	converted, err := c.converter.Convert(obj, to)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't convert %s to %s", obj, to)
+	}
+	return converted, nil
+}
@@ -0,0 +1,88 @@
+package objdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// WithMaxDepth shows the differing paths of the payloads down to the depth, where a path with more keys
+// is shown as "subtree changed" at the depth rather than expanding every leaf under it.
+// It keeps the diffs of deeply nested specs like the ones of large custom resources short. 0 shows every leaf.
+func WithMaxDepth(depth int) Option {
+	return func(d *Diff) {
+		d.maxDepth = depth
+	}
+}
+
+// depthReporter renders the differing leaves collapsing the ones deeper than the depth into their subtrees.
+type depthReporter struct {
+	depth int
+	path  cmp.Path
+	last  string
+	b     strings.Builder
+}
+
+// diffDepth returns the diff of the payloads by path down to the depth.
+func diffDepth(x, y any, depth int, opts []cmp.Option) string {
+	r := &depthReporter{depth: depth}
+	cmp.Equal(x, y, append(opts, cmp.Reporter(r))...)
+	return r.b.String()
+}
+
+func (r *depthReporter) PushStep(ps cmp.PathStep) {
+	r.path = append(r.path, ps)
+}
+
+func (r *depthReporter) Report(rs cmp.Result) {
+	if rs.Equal() {
+		return
+	}
+	if subtree, ok := r.collapsed(); ok {
+		// the leaves are reported in order, so the ones under the same subtree are next to each other
+		if subtree != r.last {
+			fmt.Fprintf(&r.b, "~ %s: subtree changed\n", subtree)
+			r.last = subtree
+		}
+		return
+	}
+	key := pathKey(r.path)
+	vx, vy := r.path.Last().Values()
+	if vx.IsValid() {
+		fmt.Fprintf(&r.b, "- %s: %s\n", key, jsonString(valueOf(vx)))
+	}
+	if vy.IsValid() {
+		fmt.Fprintf(&r.b, "+ %s: %s\n", key, jsonString(valueOf(vy)))
+	}
+}
+
+func (r *depthReporter) PopStep() {
+	r.path = r.path[:len(r.path)-1]
+}
+
+// collapsed returns the key of the subtree at the depth if the current path is deeper.
+func (r *depthReporter) collapsed() (string, bool) {
+	n := 0
+	for i, ps := range r.path {
+		switch ps.(type) {
+		case cmp.MapIndex, cmp.SliceIndex:
+			n++
+		default:
+			continue
+		}
+		if n > r.depth {
+			return pathKey(r.path[:i]), true
+		}
+	}
+	return "", false
+}
+
+func jsonString(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
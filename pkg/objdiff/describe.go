@@ -0,0 +1,158 @@
+package objdiff
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitoku/difftool/pkg/util"
+)
+
+// NamedOption is an option of the comparison with the description of what it does like "ignore spec.replicas",
+// so that the options in effect can be reported.
+type NamedOption struct {
+	Name   string
+	Option cmp.Option
+}
+
+// OptionEffect is the paths an option hides, which would differ without it.
+type OptionEffect struct {
+	NamedOption
+	Paths []string
+}
+
+// NamedOptionsFor returns the options applicable to the version with their names.
+func NamedOptionsFor(v *util.Version, opts ...VersionedOption) []NamedOption {
+	var out []NamedOption
+	for _, o := range opts {
+		if o.Constraint == nil || o.Constraint.Matches(v) {
+			out = append(out, NamedOption{Name: o.Name, Option: o.Option})
+		}
+	}
+	return out
+}
+
+// defaultOptions returns the options every comparison of the local object by the Diff takes, which withDefaultOptions adds.
+func (d *Diff) defaultOptions(local *Object) []NamedOption {
+	var out []NamedOption
+	if len(d.ignoredAnnotations) != 0 {
+		out = append(out, NamedOption{
+			Name:   "ignore the annotations " + strings.Join(d.ignoredAnnotations, ", "),
+			Option: IgnoreAnnotations(d.ignoredAnnotations...),
+		})
+	}
+	if len(d.ignoredAnnotationPrefixes) != 0 {
+		out = append(out, NamedOption{
+			Name:   "ignore the annotations prefixed with " + strings.Join(d.ignoredAnnotationPrefixes, ", "),
+			Option: IgnoreAnnotationPrefixes(d.ignoredAnnotationPrefixes...),
+		})
+	}
+	if !d.keepManagedFields {
+		out = append(out, NamedOption{Name: "ignore managedFields", Option: IgnoreManagedFields()})
+	}
+	if fields := d.generatedFieldsOf(local); len(fields) != 0 {
+		out = append(out, ignoreGenerated(fields))
+	}
+	return out
+}
+
+// AppliedOptions returns the options applied to the comparison of the local object in the order they're applied,
+// which are the given ones, the defaults of the Diff, the comparers registered for the kind
+// and the keys of IgnoreAnnotation.
+func (d *Diff) AppliedOptions(local *Object, opts []NamedOption) []NamedOption {
+	out := append(append([]NamedOption{}, opts...), d.defaultOptions(local)...)
+	gk := local.GroupVersionKind().GroupKind()
+	comparersMu.RLock()
+	registered := comparers[gk]
+	comparersMu.RUnlock()
+	if len(registered) != 0 {
+		out = append(out, NamedOption{Name: "the comparers registered for " + gk.String(), Option: cmp.Options(registered)})
+	}
+	if keys := annotatedList(local, IgnoreAnnotation); len(keys) != 0 {
+		out = append(out, NamedOption{
+			Name:   "ignore " + strings.Join(keys, ", ") + " by " + IgnoreAnnotation,
+			Option: IgnoreMapEntries(keys),
+		})
+	}
+	return out
+}
+
+// OptionEffects returns every option applied to the comparison of the objects with the paths it hides,
+// which are the ones differing only without the option while all the others are applied.
+// The paths an option hides together with another one aren't attributed to either.
+func (d *Diff) OptionEffects(local, remote *Object, opts []NamedOption) ([]OptionEffect, error) {
+	x, y, err := d.comparable(local, remote)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	applied := d.AppliedOptions(x, opts)
+	all := make([]cmp.Option, 0, len(applied))
+	for _, o := range applied {
+		all = append(all, o.Option)
+	}
+	shown, err := d.differingPaths(x, y, all)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	out := make([]OptionEffect, 0, len(applied))
+	for i, o := range applied {
+		others := make([]cmp.Option, 0, len(all)-1)
+		others = append(append(others, all[:i]...), all[i+1:]...)
+		paths, err := d.differingPaths(x, y, others)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		var hidden []string
+		for p := range paths {
+			if !shown[p] {
+				hidden = append(hidden, p)
+			}
+		}
+		sort.Strings(hidden)
+		out = append(out, OptionEffect{NamedOption: o, Paths: hidden})
+	}
+	return out, nil
+}
+
+// differingPaths returns the paths differing in the payloads of the comparable objects with exactly the options.
+func (d *Diff) differingPaths(local, remote *Object, opts []cmp.Option) (map[string]bool, error) {
+	keys, err := d.differingKeys(local, remote, opts)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	out := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		out[strings.Join(k, ".")] = true
+	}
+	return out, nil
+}
+
+// differingKeys returns the keys of the differing leaf paths of the payloads of the comparable objects
+// with exactly the options, which start with the field with WithFields.
+func (d *Diff) differingKeys(local, remote *Object, opts []cmp.Option) ([][]string, error) {
+	fields := d.fieldsOf(local)
+	if len(fields) == 0 {
+		xp, yp, err := extract(local, remote)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		r := &pathReporter{}
+		cmp.Equal(xp, yp, append(opts, cmp.Reporter(r))...)
+		return r.keys, nil
+	}
+	var out [][]string
+	for _, f := range fields {
+		xp, yp, err := extractFields(local, remote, f)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		r := &pathReporter{}
+		cmp.Equal(xp, yp, append(opts, cmp.Reporter(r))...)
+		for _, k := range r.keys {
+			out = append(out, append([]string{f}, k...))
+		}
+	}
+	return out, nil
+}
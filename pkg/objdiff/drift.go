@@ -0,0 +1,90 @@
+package objdiff
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	severityMu sync.RWMutex
+	severities = map[schema.GroupKind]map[string]float64{}
+)
+
+// RegisterSeverity weighs the differing paths under the field of the kind in the drift scores of WithDriftScores,
+// like RegisterSeverity(schema.GroupKind{Group: "apps", Kind: "Deployment"}, "template.spec.containers.*.image", 10).
+// The field is matched like RegisterServerDefaults, and the longest field matching a path wins.
+// The paths under no registered field weigh 1.
+func RegisterSeverity(gk schema.GroupKind, field string, weight float64) {
+	severityMu.Lock()
+	defer severityMu.Unlock()
+	if severities[gk] == nil {
+		severities[gk] = make(map[string]float64)
+	}
+	severities[gk][field] = weight
+}
+
+// WithDriftScores sets DriftScore of the ClassChanged results to the sum of the weights of the differing leaf paths,
+// so that the most drifted objects can be fixed first.
+func WithDriftScores() Option {
+	return func(d *Diff) {
+		d.driftScores = true
+	}
+}
+
+// SortByDrift sorts the results by DriftScore in the descending order. Results of the same score keep their order.
+func SortByDrift(results []DiffResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].DriftScore > results[j].DriftScore
+	})
+}
+
+// scoreDrift sets the drift scores of the ClassChanged results compared with the remote objects.
+func (d *Diff) scoreDrift(results []DiffResult, remote []*Object, opts []cmp.Option) error {
+	if !d.driftScores {
+		return nil
+	}
+	byKey := make(map[string]*Object, len(remote))
+	for _, o := range remote {
+		byKey[skipKey(o)] = o
+	}
+	for i, r := range results {
+		o, ok := byKey[skipKey(r.Object)]
+		if !ok || r.Class != ClassChanged {
+			continue
+		}
+		x, y, err := d.comparable(r.Object, o)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		keys, err := d.differingKeys(x, y, withIgnoredKeys(x, withComparers(x, d.withDefaultOptions(x, opts))))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		results[i].DriftScore = driftScore(x.GroupVersionKind().GroupKind(), keys)
+	}
+	return nil
+}
+
+// driftScore sums the weights of the paths of the kind.
+func driftScore(gk schema.GroupKind, paths [][]string) float64 {
+	severityMu.RLock()
+	weights := severities[gk]
+	severityMu.RUnlock()
+	var score float64
+	for _, keys := range paths {
+		weight, longest := 1.0, 0
+		for field, w := range weights {
+			pattern := strings.Split(field, ".")
+			if len(pattern) > longest && len(keys) >= len(pattern) && matchesPattern(keys[:len(pattern)], pattern) {
+				weight, longest = w, len(pattern)
+			}
+		}
+		score += weight
+	}
+	return score
+}
@@ -0,0 +1,74 @@
+package objdiff
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/cockroachdb/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// WithDryRunCreate creates the objects missing from the cluster with server-side dry-run,
+// so that the ones the server would reject by admission, quotas or validation are reported as ClassRejected
+// with the reasons rather than as ClassMissing. Nothing is persisted by the creation.
+func WithDryRunCreate() Option {
+	return func(d *Diff) {
+		d.dryRunCreate = true
+	}
+}
+
+// dryRunMissing replaces the ClassMissing results whose objects the server rejects to create with ClassRejected ones.
+func (d *Diff) dryRunMissing(results []DiffResult) ([]DiffResult, error) {
+	if !d.dryRunCreate {
+		return results, nil
+	}
+	for i, r := range results {
+		if r.Class != ClassMissing {
+			continue
+		}
+		reason, err := d.dryRunCreateObj(r.Object)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if reason != "" {
+			results[i].Class, results[i].Diff = ClassRejected, reason+"\n"
+		}
+	}
+	return results, nil
+}
+
+// dryRunCreateObj creates the object with dry-run, and returns why the server rejects it if it does.
+// The reasons of sensitive objects only tell the kind of the failure since the messages may have the values.
+func (d *Diff) dryRunCreateObj(obj *Object) (string, error) {
+	mapping, err := d.getResource(obj.APIVersion, obj.Kind)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	u := &unstructured.Unstructured{}
+	if err := u.UnmarshalJSON(b); err != nil {
+		return "", errors.WithStack(err)
+	}
+	opts := v1.CreateOptions{DryRun: []string{v1.DryRunAll}}
+	if obj.Namespace != "" {
+		_, err = d.client.Resource(mapping.Resource).Namespace(obj.Namespace).Create(context.Background(), u, opts)
+	} else {
+		_, err = d.client.Resource(mapping.Resource).Create(context.Background(), u, opts)
+	}
+	var status kerrors.APIStatus
+	switch {
+	case err == nil, kerrors.IsAlreadyExists(err):
+		// it may have been created since it was fetched
+		return "", nil
+	case !errors.As(err, &status):
+		return "", errors.Wrapf(err, "couldn't create %s with dry-run", obj)
+	case !d.revealSecrets && d.isSensitive(obj):
+		return string(kerrors.ReasonForError(err)), nil
+	}
+	return err.Error(), nil
+}
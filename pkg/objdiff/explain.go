@@ -0,0 +1,450 @@
+package objdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/bitoku/difftool/pkg/util"
+)
+
+// Cause is the likely cause of a change told by Explain.
+type Cause string
+
+const (
+	// CauseDefaulted means the field isn't in the manifest and was set by the server.
+	CauseDefaulted Cause = "defaulted by server"
+	// CauseWebhook means the field isn't in the manifest but is owned by the manager applying it,
+	// which is what the mutation of an admission webhook looks like.
+	CauseWebhook Cause = "mutated by admission webhook"
+	// CauseManager means the field is owned by another field manager like a controller.
+	CauseManager Cause = "managed by another field manager"
+	// CauseImmutable means the field can't be changed once the object is created.
+	CauseImmutable Cause = "immutable field"
+	// CauseDrift means none of the others, so the object has likely been changed by hand.
+	CauseDrift Cause = "genuine drift"
+)
+
+// Explanation is a differing path of the payloads with its likely cause.
+type Explanation struct {
+	// PathDiff is the difference, where Old is the value in the manifest and New is the one in the cluster
+	// regardless of the perspective.
+	PathDiff
+	Cause Cause
+	// Managers are the field managers owning the path in the cluster other than the one applying the manifest.
+	Managers []string
+}
+
+// String returns the path with the cause like "replicas: managed by another field manager (kube-controller-manager)".
+func (e Explanation) String() string {
+	if e.Cause == CauseManager {
+		return fmt.Sprintf("%s: %s (%s)", e.Path, e.Cause, strings.Join(e.Managers, ", "))
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Cause)
+}
+
+var (
+	explainMu sync.RWMutex
+	// serverDefaults are the fields defaulted by the builtin kinds and
+	// immutableFields are the ones rejected after the creation, relative to the payloads.
+	serverDefaults  = builtinServerDefaults()
+	immutableFields = map[schema.GroupKind][]string{
+		{Group: "apps", Kind: "Deployment"}:  {"selector"},
+		{Group: "apps", Kind: "DaemonSet"}:   {"selector"},
+		{Group: "apps", Kind: "StatefulSet"}: {"selector", "serviceName", "volumeClaimTemplates", "podManagementPolicy"},
+		{Group: "batch", Kind: "Job"}:        {"selector", "template", "completionMode"},
+		{Kind: "Service"}:                    {"clusterIP", "clusterIPs"},
+		{Kind: "PersistentVolumeClaim"}:      {"accessModes", "selector", "storageClassName", "volumeMode", "dataSource"},
+	}
+)
+
+// podSpecDefaults are the fields of pod specs defaulted by the server.
+var podSpecDefaults = []string{
+	"dnsPolicy",
+	"restartPolicy",
+	"schedulerName",
+	"securityContext",
+	"terminationGracePeriodSeconds",
+	"containers.*.imagePullPolicy",
+	"containers.*.terminationMessagePath",
+	"containers.*.terminationMessagePolicy",
+	"initContainers.*.imagePullPolicy",
+	"initContainers.*.terminationMessagePath",
+	"initContainers.*.terminationMessagePolicy",
+}
+
+func builtinServerDefaults() map[schema.GroupKind][]string {
+	template := func(paths ...string) []string {
+		for _, p := range podSpecDefaults {
+			paths = append(paths, "template.spec."+p)
+		}
+		return paths
+	}
+	return map[schema.GroupKind][]string{
+		{Kind: "Pod"}:                        append([]string{"enableServiceLinks", "preemptionPolicy", "priority"}, podSpecDefaults...),
+		{Group: "apps", Kind: "Deployment"}:  template("progressDeadlineSeconds", "revisionHistoryLimit", "strategy"),
+		{Group: "apps", Kind: "DaemonSet"}:   template("revisionHistoryLimit", "updateStrategy"),
+		{Group: "apps", Kind: "StatefulSet"}: template("persistentVolumeClaimRetentionPolicy", "podManagementPolicy", "revisionHistoryLimit", "updateStrategy"),
+		{Group: "batch", Kind: "Job"}:        template("backoffLimit", "completionMode", "completions", "parallelism", "suspend"),
+		{Kind: "Service"}:                    {"clusterIP", "clusterIPs", "internalTrafficPolicy", "ipFamilies", "ipFamilyPolicy", "sessionAffinity", "ports.*.protocol", "ports.*.targetPort"},
+	}
+}
+
+// RegisterServerDefaults registers the fields of the kind the server defaults, which Explain tells as CauseDefaulted.
+// The fields are the keys of the payloads with "*" for the indices like "template.spec.dnsPolicy",
+// and the ones under them are defaulted as well. It replaces the registered ones of the kind if any, including the builtin ones.
+func RegisterServerDefaults(gk schema.GroupKind, fields ...string) {
+	explainMu.Lock()
+	defer explainMu.Unlock()
+	serverDefaults[gk] = fields
+}
+
+// RegisterImmutableFields registers the fields of the kind which can't be changed once the object is created,
+// which Explain tells as CauseImmutable. The fields are matched like RegisterServerDefaults.
+// It replaces the registered ones of the kind if any, including the builtin ones.
+func RegisterImmutableFields(gk schema.GroupKind, fields ...string) {
+	explainMu.Lock()
+	defer explainMu.Unlock()
+	immutableFields[gk] = fields
+}
+
+// versionedFields are fields registered only for the versions matching the constraint.
+type versionedFields struct {
+	constraint *util.Constraint
+	fields     []string
+}
+
+// versionedImmutableFields are the fields of RegisterVersionedImmutableFields.
+var versionedImmutableFields = make(map[schema.GroupKind][]versionedFields)
+
+// RegisterVersionedImmutableFields registers the immutable fields of the kind like RegisterImmutableFields
+// only for the versions of the cluster matching the constraint, like a field which became immutable in 1.24 with ">=1.24".
+// They're added to the other immutable fields of the kind including the ones registered with this before.
+func RegisterVersionedImmutableFields(gk schema.GroupKind, constraint *util.Constraint, fields ...string) {
+	explainMu.Lock()
+	defer explainMu.Unlock()
+	versionedImmutableFields[gk] = append(versionedImmutableFields[gk], versionedFields{constraint: constraint, fields: fields})
+}
+
+// immutableFieldsOf returns the immutable fields of the kind on the version of the cluster.
+// Only the ones for any version are returned if the version can't be known like in NewStatic.
+func (d *Diff) immutableFieldsOf(gk schema.GroupKind) ([]string, error) {
+	explainMu.RLock()
+	fields := append([]string(nil), immutableFields[gk]...)
+	versioned := versionedImmutableFields[gk]
+	explainMu.RUnlock()
+	if len(versioned) == 0 || d.discovery == nil && d.serverVersion == nil {
+		return fields, nil
+	}
+	v, err := d.ServerVersion()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, f := range versioned {
+		if f.constraint.Matches(v) {
+			fields = append(fields, f.fields...)
+		}
+	}
+	return fields, nil
+}
+
+// WithExplanations appends the likely cause of every differing path to the diffs of changed objects.
+func WithExplanations() Option {
+	return func(d *Diff) {
+		d.explain = true
+	}
+}
+
+// Explain tells the likely causes of the differences of the payloads of the objects by heuristics.
+// The fields missing from the manifest are defaulted by the server if they're registered or owned by no field manager,
+// or mutated by an admission webhook if they're owned only by the manager applying the manifest.
+// Otherwise the registered immutable fields come first, then the fields owned by other managers are managed by them,
+// and the rest is drift.
+// The manager applying the manifest is the one of WithFieldManager, or the managers applying the object with
+// server-side apply or owning the last applied configuration of kubectl otherwise.
+func (d *Diff) Explain(local, remote *Object, opts ...cmp.Option) ([]Explanation, error) {
+	x, y, err := d.comparable(local, remote)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return d.explainPaths(x, y, remote, opts)
+}
+
+// explained appends the causes of the diff of the comparable objects if WithExplanations is given,
+// where managed is the remote object keeping its managedFields.
+func (d *Diff) explained(diff string, local, remote, managed *Object, opts []cmp.Option) (string, error) {
+	if !d.explain || diff == "" {
+		return diff, nil
+	}
+	explanations, err := d.explainPaths(local, remote, managed, opts)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if len(explanations) == 0 {
+		return diff, nil
+	}
+	var b strings.Builder
+	b.WriteString(diff)
+	b.WriteString("# causes\n")
+	for _, e := range explanations {
+		fmt.Fprintf(&b, "%s\n", e)
+	}
+	return b.String(), nil
+}
+
+type explainedPayload struct {
+	xp, yp any
+	// prefix is the field of the payload with WithFields
+	prefix string
+	// roots are the fieldsets in managedFields and the values of the remote object the payload is made of
+	roots []fieldsRoot
+}
+
+type fieldsRoot struct {
+	field string
+	value any
+}
+
+func (d *Diff) explainPaths(local, remote, managed *Object, opts []cmp.Option) ([]Explanation, error) {
+	opts = withIgnoredKeys(local, withComparers(local, d.withDefaultOptions(local, opts)))
+	fields := d.fieldsOf(local)
+	var payloads []explainedPayload
+	if len(fields) == 0 {
+		xp, yp, err := extract(local, remote)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		payloads = append(payloads, explainedPayload{xp: xp, yp: yp, roots: payloadRoots(local, managed, "")})
+	}
+	for _, f := range fields {
+		xp, yp, err := extractFields(local, remote, f)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		payloads = append(payloads, explainedPayload{xp: xp, yp: yp, prefix: f + ".", roots: payloadRoots(local, managed, f)})
+	}
+
+	managers, err := parseManagedFields(managed)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ours := d.applyingManagers(managed)
+	gk := local.GroupVersionKind().GroupKind()
+	explainMu.RLock()
+	defaults := serverDefaults[gk]
+	explainMu.RUnlock()
+	immutable, err := d.immutableFieldsOf(gk)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var out []Explanation
+	for _, p := range payloads {
+		r := &pathReporter{}
+		cmp.Equal(p.xp, p.yp, append(opts, cmp.Reporter(r))...)
+		for i, diff := range r.diffs {
+			keys := r.keys[i]
+			e := Explanation{PathDiff: diff}
+			e.Path = p.prefix + diff.Path
+			var owners []string
+			for _, m := range managers {
+				if m.owns(p.roots, keys) {
+					owners = append(owners, m.name)
+				}
+			}
+			sort.Strings(owners)
+			e.Cause, e.Managers = explainCause(e, owners, ours, len(managers) != 0, p.prefix == "" && underAny(keys, defaults), p.prefix == "" && underAny(keys, immutable))
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// explainCause returns the cause of the change with the owners of the path other than ours.
+func explainCause(e Explanation, owners []string, ours map[string]bool, tracked, defaulted, immutable bool) (Cause, []string) {
+	var others []string
+	for _, m := range owners {
+		if !ours[m] {
+			others = append(others, m)
+		}
+	}
+	if e.Old == nil {
+		switch {
+		case defaulted, tracked && len(owners) == 0:
+			return CauseDefaulted, others
+		case len(owners) != 0 && len(others) == 0:
+			return CauseWebhook, others
+		}
+	}
+	if immutable {
+		return CauseImmutable, others
+	}
+	if len(others) != 0 {
+		return CauseManager, others
+	}
+	return CauseDrift, others
+}
+
+// payloadRoots returns where the payload of the field, or the one of the extractor if it's empty, is in managedFields.
+// It's empty for custom extractors since their payloads can be anything.
+func payloadRoots(local, managed *Object, field string) []fieldsRoot {
+	gk := local.GroupVersionKind().GroupKind()
+	if field == "" {
+		extractorsMu.RLock()
+		_, custom := extractors[gk]
+		extractorsMu.RUnlock()
+		switch {
+		case gk.Group == "" && (gk.Kind == "ConfigMap" || gk.Kind == "Secret"):
+			field = "data"
+		case custom:
+			return nil
+		default:
+			field = "spec"
+		}
+	}
+	switch field {
+	case "spec":
+		return []fieldsRoot{{field: "f:spec", value: managed.Spec}}
+	case "data":
+		return []fieldsRoot{{field: "f:data", value: managed.Data}, {field: "f:binaryData", value: managed.BinaryData}}
+	case "status":
+		return []fieldsRoot{{field: "f:status", value: managed.Status}}
+	}
+	return nil
+}
+
+type managedEntry struct {
+	name   string
+	fields map[string]any
+}
+
+// parseManagedFields returns the fieldsets of the managers merged by the names.
+func parseManagedFields(obj *Object) ([]managedEntry, error) {
+	var out []managedEntry
+	seen := make(map[string]bool)
+	for _, e := range obj.ManagedFields {
+		if seen[e.Manager] {
+			continue
+		}
+		seen[e.Manager] = true
+		fields, err := ownedFields(obj, e.Manager)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		out = append(out, managedEntry{name: e.Manager, fields: fields})
+	}
+	return out, nil
+}
+
+// owns tells if the manager owns the path under any of the roots.
+func (m managedEntry) owns(roots []fieldsRoot, keys []string) bool {
+	for _, r := range roots {
+		if fields, ok := m.fields[r.field].(map[string]any); ok && ownsPath(fields, r.value, keys) {
+			return true
+		}
+	}
+	return false
+}
+
+// ownsPath walks the fieldset along the keys with the value it describes,
+// where an empty fieldset owns everything under it.
+func ownsPath(fields map[string]any, v any, keys []string) bool {
+	for _, k := range keys {
+		if len(fields) == 0 {
+			return true
+		}
+		switch x := v.(type) {
+		case []any:
+			i, e, ok := elementAt(x, k)
+			if !ok {
+				return false
+			}
+			sub, ok := elementFields(fields, i, e)
+			if !ok {
+				return false
+			}
+			fields, v = sub, e
+		default:
+			sub, ok := fields["f:"+k].(map[string]any)
+			if !ok {
+				return false
+			}
+			m, _ := v.(map[string]any)
+			fields, v = sub, m[k]
+		}
+	}
+	return true
+}
+
+// elementAt finds the element of the list by its index or by the merge keys like "name=app".
+func elementAt(s []any, key string) (int, any, bool) {
+	if i, err := strconv.Atoi(key); err == nil {
+		if i < 0 || i >= len(s) {
+			return 0, nil, false
+		}
+		return i, s[i], true
+	}
+	for i, e := range s {
+		m, ok := e.(map[string]any)
+		if !ok {
+			continue
+		}
+		matched := true
+		for _, kv := range strings.Split(key, ",") {
+			k, v, _ := strings.Cut(kv, "=")
+			if fmt.Sprintf("%v", m[k]) != v {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i, e, true
+		}
+	}
+	return 0, nil, false
+}
+
+// applyingManagers returns the managers applying the manifests to the object.
+func (d *Diff) applyingManagers(obj *Object) map[string]bool {
+	ours := make(map[string]bool)
+	if d.fieldManager != "" {
+		ours[d.fieldManager] = true
+		return ours
+	}
+	for _, e := range obj.ManagedFields {
+		if e.Operation == v1.ManagedFieldsOperationApply || ownsLastApplied(e) {
+			ours[e.Manager] = true
+		}
+	}
+	return ours
+}
+
+func ownsLastApplied(e v1.ManagedFieldsEntry) bool {
+	if e.FieldsV1 == nil {
+		return false
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(e.FieldsV1.Raw, &fields); err != nil {
+		return false
+	}
+	return ownsPath(fields, nil, []string{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"})
+}
+
+// underAny tells if the keys are any of the patterns or under them.
+func underAny(keys []string, patterns []string) bool {
+	for _, p := range patterns {
+		pattern := strings.Split(p, ".")
+		if len(keys) >= len(pattern) && matchesPattern(keys[:len(pattern)], pattern) {
+			return true
+		}
+	}
+	return false
+}
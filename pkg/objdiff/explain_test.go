@@ -0,0 +1,48 @@
+package objdiff
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/bitoku/difftool/pkg/util"
+)
+
+func TestImmutableFieldsOf(t *testing.T) {
+	gk := schema.GroupKind{Group: "example.com", Kind: "Gadget"}
+	RegisterImmutableFields(gk, "name")
+	since, err := util.ParseConstraint(">=1.24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterVersionedImmutableFields(gk, since, "size")
+	tests := []struct {
+		version string
+		want    []string
+	}{
+		{version: "1.23.9", want: []string{"name"}},
+		{version: "1.24.0", want: []string{"name", "size"}},
+		{version: "v1.28.4+k3s1", want: []string{"name", "size"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			v, err := util.ParseVersion(tt.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := &Diff{serverVersion: v}
+			got, err := d.immutableFieldsOf(gk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("immutableFieldsOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	// the version of a static diff is unknown
+	if got, err := (&Diff{}).immutableFieldsOf(gk); err != nil || fmt.Sprint(got) != "[name]" {
+		t.Errorf("immutableFieldsOf() = %v, %v, want [name]", got, err)
+	}
+}
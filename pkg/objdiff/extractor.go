@@ -0,0 +1,108 @@
+package objdiff
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Extractor returns the part of an object which is compared.
+type Extractor func(obj *Object) (any, error)
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   = map[schema.GroupKind]Extractor{
+		{Kind: "ConfigMap"}: extractData,
+		{Kind: "Secret"}:    extractData,
+	}
+)
+
+// RegisterExtractor registers the extractor for the given kind.
+// It replaces the registered one if any, including the builtin ones for ConfigMap and Secret.
+// Kinds without an extractor are compared by their spec.
+func RegisterExtractor(gk schema.GroupKind, fn Extractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors[gk] = fn
+}
+
+// DataMap is the payload of ConfigMaps and Secrets.
+// It's a distinct type so that options can be scoped to data keys.
+type DataMap map[string]any
+
+// BinaryValue is a value of binaryData of a ConfigMap in base64.
+// It's a distinct type from the values of data so that a key moving between data and binaryData
+// is shown as the change of the encoding of the key rather than the key removed and added.
+type BinaryValue string
+
+// ConflictingValue is the values of a key under both data and binaryData, which is invalid but may happen.
+type ConflictingValue struct {
+	Data       any
+	BinaryData BinaryValue
+}
+
+// extractData returns the keys of data and binaryData in a DataMap since a key is identified by its name
+// regardless of the map it's in.
+func extractData(obj *Object) (any, error) {
+	binary, _ := obj.BinaryData.(map[string]any)
+	m, ok := obj.Data.(map[string]any)
+	if len(binary) == 0 {
+		if ok {
+			return DataMap(m), nil
+		}
+		return obj.Data, nil
+	}
+	out := make(DataMap, len(m)+len(binary))
+	for k, v := range m {
+		out[k] = v
+	}
+	for k, v := range binary {
+		s, _ := v.(string)
+		if d, ok := out[k]; ok {
+			out[k] = ConflictingValue{Data: d, BinaryData: BinaryValue(s)}
+			continue
+		}
+		out[k] = BinaryValue(s)
+	}
+	return out, nil
+}
+
+// isDataValue tells if the path is a value of a data key.
+func isDataValue(path cmp.Path) bool {
+	if len(path) < 2 || path.Index(0).Type() != reflect.TypeOf(DataMap(nil)) {
+		return false
+	}
+	_, ok := path.Index(1).(cmp.MapIndex)
+	return ok
+}
+
+func extractSpec(obj *Object) (any, error) {
+	return obj.Spec, nil
+}
+
+func getExtractor(gk schema.GroupKind) Extractor {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	if fn, ok := extractors[gk]; ok {
+		return fn
+	}
+	return extractSpec
+}
+
+// extract returns the comparable payloads of the both objects.
+// The extractor is chosen by the kind of obj1 so that both sides are extracted in the same way.
+func extract(obj1, obj2 *Object) (any, any, error) {
+	fn := getExtractor(obj1.GroupVersionKind().GroupKind())
+	x, err := fn(obj1)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "couldn't extract %s", obj1)
+	}
+	y, err := fn(obj2)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "couldn't extract %s", obj2)
+	}
+	return x, y, nil
+}
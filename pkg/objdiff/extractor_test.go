@@ -0,0 +1,57 @@
+package objdiff
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExtractBinaryData(t *testing.T) {
+	withBinary := func(data, binaryData map[string]any) *Object {
+		obj := configMap("cm", data)
+		if binaryData != nil {
+			obj.BinaryData = binaryData
+		}
+		return obj
+	}
+	tests := []struct {
+		name          string
+		local, remote *Object
+		want          []PathDiff
+	}{
+		{
+			name:   "moved to binaryData",
+			local:  withBinary(map[string]any{"k": "aGVsbG8=", "other": "v"}, nil),
+			remote: withBinary(map[string]any{"other": "v"}, map[string]any{"k": "aGVsbG8="}),
+			want:   []PathDiff{{Path: "k", Old: "aGVsbG8=", New: BinaryValue("aGVsbG8=")}},
+		},
+		{
+			name:   "moved to data",
+			local:  withBinary(nil, map[string]any{"k": "aGVsbG8="}),
+			remote: withBinary(map[string]any{"k": "hello"}, nil),
+			want:   []PathDiff{{Path: "k", Old: BinaryValue("aGVsbG8="), New: "hello"}},
+		},
+		{
+			name:   "same binaryData",
+			local:  withBinary(map[string]any{"other": "v"}, map[string]any{"k": "aGVsbG8="}),
+			remote: withBinary(map[string]any{"other": "v"}, map[string]any{"k": "aGVsbG8="}),
+		},
+		{
+			name:   "under both",
+			local:  withBinary(map[string]any{"k": "hello"}, nil),
+			remote: withBinary(map[string]any{"k": "hello"}, map[string]any{"k": "aGVsbG8="}),
+			want:   []PathDiff{{Path: "k", Old: "hello", New: ConflictingValue{Data: "hello", BinaryData: "aGVsbG8="}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffObjPaths(tt.local, tt.remote)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// a key moving between the maps is a change of the key rather than one removed and another added
+			if fmt.Sprintf("%#v", got) != fmt.Sprintf("%#v", tt.want) {
+				t.Errorf("DiffObjPaths() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package objdiff
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cockroachdb/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// WithPartialFetch makes lists be fetched as metadata first when remote objects are skipped by WithModifiedSince,
+// and only the fresh ones are fetched in full, which saves the transfer of large objects nobody compares.
+// It falls back to fetching the full list if the metadata can't be listed.
+func WithPartialFetch() Option {
+	return func(d *Diff) {
+		d.partialFetch = true
+	}
+}
+
+// listOptions selects the remote objects out of the excluded namespaces on the server,
+// except the namespaces of the local objects. pruneExcluded still prunes them in case the selector is dropped.
+func (d *Diff) listOptions(local []*Object) v1.ListOptions {
+	if len(d.excludedNamespaces) == 0 {
+		return v1.ListOptions{}
+	}
+	localNamespaces := make(map[string]bool)
+	for _, o := range local {
+		localNamespaces[o.Namespace] = true
+	}
+	var selectors []fields.Selector
+	for ns := range d.excludedNamespaces {
+		if !localNamespaces[ns] {
+			selectors = append(selectors, fields.OneTermNotEqualSelector("metadata.namespace", ns))
+		}
+	}
+	if len(selectors) == 0 {
+		return v1.ListOptions{}
+	}
+	// sort them to make the requests stable
+	sort.Slice(selectors, func(i, j int) bool { return selectors[i].String() < selectors[j].String() })
+	return v1.ListOptions{FieldSelector: fields.AndSelectors(selectors...).String()}
+}
+
+// streamRemoteObjs lists the remote objects of the mapping page by page like streamRemoteObjsIn,
+// fetching the metadata first if WithPartialFetch is given, which passes all of them at once.
+func (d *Diff) streamRemoteObjs(mapping *meta.RESTMapping, namespace string, opts v1.ListOptions, emit func([]*Object) error) error {
+	if !d.partialFetch || d.modifiedSince.IsZero() || d.metadata == nil {
+		return d.streamRemoteObjsIn(mapping.Resource, namespace, opts, emit)
+	}
+	list, err := d.metadata.Resource(mapping.Resource).Namespace(namespace).List(context.Background(), opts)
+	if err != nil {
+		return d.streamRemoteObjsIn(mapping.Resource, namespace, opts, emit)
+	}
+	apiVersion, kind := mapping.GroupVersionKind.ToAPIVersionAndKind()
+	var out []*Object
+	for _, i := range list.Items {
+		o := &Object{TypeMeta: v1.TypeMeta{APIVersion: apiVersion, Kind: kind}, ObjectMeta: i.ObjectMeta}
+		if d.isStale(o) || !d.matchesName(o) {
+			// it's pruned later anyway without the rest of the object
+			out = append(out, o)
+			continue
+		}
+		full, err := d.getRemoteObj(mapping.Resource, o)
+		if kerrors.IsNotFound(errors.Cause(err)) {
+			// deleted since listed
+			continue
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		out = append(out, full)
+	}
+	return emit(out)
+}
+
+// Fetch returns the remote counterpart of obj, which is a List of the objects its items are compared with if it's a list,
+// so that it can be diffed with another cluster as the local object.
+func (d *Diff) Fetch(apiVersion, kind string, obj *Object) (*Object, error) {
+	mapping, err := d.getResource(apiVersion, kind)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	obj = d.resolveNamespace(obj)
+	if !obj.IsList() {
+		remote, err := d.getRemoteObj(mapping.Resource, obj)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return remote, nil
+	}
+	mappings, items, err := d.listMappings(mapping, obj.Items)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	remote, forbidden, err := d.listRemote(mappings, items)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for gk := range forbidden {
+		return nil, errors.Newf("the access to list %s is denied", gk)
+	}
+	remote = d.pruneExcluded(items, remote)
+	_, remote = d.pruneStale(items, remote)
+	_, remote = d.pruneNames(items, remote)
+	return &Object{TypeMeta: v1.TypeMeta{APIVersion: "v1", Kind: "List"}, Items: remote}, nil
+}
@@ -0,0 +1,104 @@
+package objdiff
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+)
+
+// WithFields compares the top-level fields like "spec", "data" and "status" instead of the payloads
+// returned by the extractors, for kinds which have more than one of them. See Perspective.DiffFields.
+func WithFields(fields ...string) Option {
+	return func(d *Diff) {
+		d.fields = fields
+	}
+}
+
+// DiffFields compares the fields of obj1 as the manifest with obj2 as the cluster.
+func DiffFields(obj1, obj2 *Object, fields []string, opts ...cmp.Option) (string, error) {
+	return PerspectiveManifest.DiffFields(obj1, obj2, fields, opts...)
+}
+
+// DiffFields compares each of the top-level fields "spec", "data" and "status", and concatenates the diffs
+// headed by the fields like "# spec". Keys of the options are relative to each field as they are to the payloads.
+// Data includes binaryData as the extractor of ConfigMaps does.
+func (p Perspective) DiffFields(local, remote *Object, fields []string, opts ...cmp.Option) (string, error) {
+	x, y := p.order(local, remote)
+	opts = withIgnoredKeys(local, withComparers(local, opts))
+	var b strings.Builder
+	for _, f := range fields {
+		xp, yp, err := extractFields(x, y, f)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		if diff := cmp.Diff(xp, yp, opts...); diff != "" {
+			fmt.Fprintf(&b, "# %s\n%s", f, diff)
+		}
+	}
+	return b.String() + p.diffFinalizers(local, remote), nil
+}
+
+// extractFields returns the field of the both objects.
+func extractFields(obj1, obj2 *Object, field string) (any, any, error) {
+	x, err := extractField(obj1, field)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	y, err := extractField(obj2, field)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return x, y, nil
+}
+
+func extractField(obj *Object, field string) (any, error) {
+	switch field {
+	case "spec":
+		return obj.Spec, nil
+	case "data":
+		return extractData(obj)
+	case "status":
+		return obj.Status, nil
+	}
+	return nil, errors.Newf("unknown field %q, it must be spec, data or status", field)
+}
+
+// samePayload is Perspective.samePayload of the fields with WithFields or FieldsAnnotation.
+// The copies returned by comparable are hashed since a transform may read the fields out of the payloads.
+func (d *Diff) samePayload(local, remote *Object) (bool, error) {
+	local, remote, err := d.comparable(local, remote)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	if d.diffOwners(local, remote) != "" {
+		return false, nil
+	}
+	fields := d.fieldsOf(local)
+	if len(fields) == 0 {
+		return d.perspective.samePayload(local, remote)
+	}
+	if local.Kind != remote.Kind || d.perspective.diffFinalizers(local, remote) != "" {
+		return false, nil
+	}
+	for _, f := range fields {
+		x, y, err := extractFields(local, remote, f)
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		if valueHash(x) != valueHash(y) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func valueHash(v any) [sha256.Size]byte {
+	var sum [sha256.Size]byte
+	h := sha256.New()
+	hashValue(h, v)
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
@@ -0,0 +1,189 @@
+package objdiff
+
+import (
+	"regexp"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// WithModifiedSince makes remote objects older than since be skipped.
+// The age is the time in the annotation in RFC3339 if it's given and set, otherwise the creation timestamp.
+// The local objects corresponding to the skipped ones are skipped as well, so they aren't reported as missing.
+func WithModifiedSince(since time.Time, annotation string) Option {
+	return func(d *Diff) {
+		d.modifiedSince = since
+		d.modifiedAnnotation = annotation
+	}
+}
+
+func (d *Diff) modifiedAt(obj *Object) time.Time {
+	if d.modifiedAnnotation != "" {
+		if v, ok := obj.Annotations[d.modifiedAnnotation]; ok {
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				return t
+			}
+		}
+	}
+	return obj.CreationTimestamp.Time
+}
+
+func (d *Diff) isStale(obj *Object) bool {
+	return !d.modifiedSince.IsZero() && d.modifiedAt(obj).Before(d.modifiedSince)
+}
+
+// pruneStale removes the stale remote objects and the local objects of the same identities.
+func (d *Diff) pruneStale(local, remote []*Object) ([]*Object, []*Object) {
+	if d.modifiedSince.IsZero() {
+		return local, remote
+	}
+	fresh, staleObjs := d.splitStale(remote)
+	stale := make(map[string]bool, len(staleObjs))
+	for _, o := range staleObjs {
+		stale[o.String()] = true
+	}
+	kept := make([]*Object, 0, len(local))
+	for _, o := range local {
+		if !stale[o.String()] {
+			kept = append(kept, o)
+		}
+	}
+	return kept, fresh
+}
+
+// splitStale splits the remote objects into the fresh and the stale ones.
+func (d *Diff) splitStale(remote []*Object) (fresh, stale []*Object) {
+	if d.modifiedSince.IsZero() {
+		return remote, nil
+	}
+	fresh = make([]*Object, 0, len(remote))
+	for _, o := range remote {
+		if d.isStale(o) {
+			stale = append(stale, o)
+			continue
+		}
+		fresh = append(fresh, o)
+	}
+	return fresh, stale
+}
+
+// SystemNamespaces is the namespaces kubernetes creates for itself.
+var SystemNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// WithExcludedNamespaces makes remote objects in the namespaces be skipped in lists.
+// Namespaces of the local objects are never skipped since they're explicitly compared.
+func WithExcludedNamespaces(namespaces ...string) Option {
+	return func(d *Diff) {
+		for _, ns := range namespaces {
+			d.excludedNamespaces[ns] = true
+		}
+	}
+}
+
+// WithItemNamespaces makes lists be compared only with the objects in the namespaces of the local objects
+// if all of them have one, so that objects in the other namespaces aren't reported as extra.
+// Otherwise lists are compared with the objects in all namespaces like `kubectl get -A`.
+func WithItemNamespaces() Option {
+	return func(d *Diff) {
+		d.itemNamespaces = true
+	}
+}
+
+// listNamespaces returns the namespaces to list the remote objects of the mapping in,
+// where the empty namespace means all of them. Items without kinds belong to the primary mapping.
+func (d *Diff) listNamespaces(mapping *meta.RESTMapping, primary bool, items []*Object) []string {
+	all := []string{""}
+	if !d.itemNamespaces {
+		return all
+	}
+	seen := make(map[string]bool)
+	var namespaces []string
+	for _, i := range items {
+		if i.GroupVersionKind().GroupKind() != mapping.GroupVersionKind.GroupKind() && !(primary && i.Kind == "") {
+			continue
+		}
+		// cluster-scoped kinds or namespaces injected at apply time
+		if i.Namespace == "" {
+			return all
+		}
+		if !seen[i.Namespace] {
+			seen[i.Namespace] = true
+			namespaces = append(namespaces, i.Namespace)
+		}
+	}
+	if len(namespaces) == 0 {
+		return all
+	}
+	return namespaces
+}
+
+// pruneExcluded removes the remote objects in the excluded namespaces which no local objects are in.
+func (d *Diff) pruneExcluded(local, remote []*Object) []*Object {
+	if len(d.excludedNamespaces) == 0 {
+		return remote
+	}
+	localNamespaces := make(map[string]bool)
+	for _, o := range local {
+		localNamespaces[o.Namespace] = true
+	}
+	kept := make([]*Object, 0, len(remote))
+	for _, o := range remote {
+		if d.excludedNamespaces[o.Namespace] && !localNamespaces[o.Namespace] {
+			continue
+		}
+		kept = append(kept, o)
+	}
+	return kept
+}
+
+// WithNamePattern makes lists compare only the local and remote objects whose names match the pattern,
+// so that a logical application like the objects named "myapp-*" is compared without the rest being missing or extra.
+func WithNamePattern(pattern *regexp.Regexp) Option {
+	return func(d *Diff) {
+		d.namePattern = pattern
+	}
+}
+
+func (d *Diff) matchesName(obj *Object) bool {
+	return d.namePattern == nil || d.namePattern.MatchString(obj.Name)
+}
+
+// pruneNames removes the local and remote objects whose names don't match the pattern of WithNamePattern.
+func (d *Diff) pruneNames(local, remote []*Object) ([]*Object, []*Object) {
+	if d.namePattern == nil {
+		return local, remote
+	}
+	matching := func(objs []*Object) []*Object {
+		kept := make([]*Object, 0, len(objs))
+		for _, o := range objs {
+			if d.matchesName(o) {
+				kept = append(kept, o)
+			}
+		}
+		return kept
+	}
+	return matching(local), matching(remote)
+}
+
+// WithIntersectionOnly only reports the objects existing on both sides,
+// so that missing and extra objects are out of scope and only the drift of their values is reported.
+func WithIntersectionOnly() Option {
+	return func(d *Diff) {
+		d.intersectionOnly = true
+	}
+}
+
+// pruneOneSided removes the results of the objects existing on one side in the intersection only mode.
+func (d *Diff) pruneOneSided(results []DiffResult) []DiffResult {
+	if !d.intersectionOnly {
+		return results
+	}
+	kept := make([]DiffResult, 0, len(results))
+	for _, r := range results {
+		if r.Class == ClassMissing || r.Class == ClassExtra {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
@@ -0,0 +1,73 @@
+package objdiff
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	generatedMu sync.RWMutex
+	// generatedFields are the fields the server generates for the builtin kinds, relative to the payloads.
+	generatedFields = builtinGeneratedFields()
+)
+
+func builtinGeneratedFields() map[schema.GroupKind][]string {
+	// the server copies serviceAccountName to the deprecated serviceAccount
+	template := []string{"template.spec.serviceAccount"}
+	return map[schema.GroupKind][]string{
+		{Kind: "Service"}:                    {"clusterIP", "clusterIPs", "healthCheckNodePort", "ports.*.nodePort"},
+		{Kind: "Pod"}:                        {"nodeName", "serviceAccount", "serviceAccountName"},
+		{Kind: "PersistentVolumeClaim"}:      {"volumeName"},
+		{Group: "apps", Kind: "Deployment"}:  template,
+		{Group: "apps", Kind: "DaemonSet"}:   template,
+		{Group: "apps", Kind: "StatefulSet"}: template,
+		{Group: "batch", Kind: "Job"}:        append([]string{"selector"}, template...),
+	}
+}
+
+// RegisterGeneratedFields registers the fields of the kind the server generates when they aren't specified,
+// like the ones a controller of a custom resource fills in, which WithoutGeneratedFields ignores.
+// The fields are matched like RegisterServerDefaults, and they're appended to the registered ones of the kind
+// including the builtin ones like the clusterIP of Services.
+func RegisterGeneratedFields(gk schema.GroupKind, fields ...string) {
+	generatedMu.Lock()
+	defer generatedMu.Unlock()
+	generatedFields[gk] = append(append([]string{}, generatedFields[gk]...), fields...)
+}
+
+// WithoutGeneratedFields ignores the registered fields the server generates like the node ports of Services
+// when they're set on only one side, so that the ones the manifests don't specify aren't reported
+// while the ones they do are still compared.
+func WithoutGeneratedFields() Option {
+	return func(d *Diff) {
+		d.ignoreGenerated = true
+	}
+}
+
+// generatedFieldsOf returns the fields generated for the kind of obj if they're ignored.
+func (d *Diff) generatedFieldsOf(obj *Object) []string {
+	if !d.ignoreGenerated {
+		return nil
+	}
+	generatedMu.RLock()
+	defer generatedMu.RUnlock()
+	return generatedFields[obj.GroupVersionKind().GroupKind()]
+}
+
+// ignoreGenerated ignores the fields under the patterns which are missing from either side.
+func ignoreGenerated(fields []string) NamedOption {
+	filter := func(path cmp.Path) bool {
+		if !underAny(pathKeys(path), fields) {
+			return false
+		}
+		x, y := path.Last().Values()
+		return !x.IsValid() || !y.IsValid()
+	}
+	return NamedOption{
+		Name:   "ignore the generated fields " + strings.Join(fields, ", ") + " set on one side",
+		Option: cmp.FilterPath(filter, cmp.Ignore()),
+	}
+}
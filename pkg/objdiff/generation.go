@@ -0,0 +1,63 @@
+package objdiff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExpectedGenerationAnnotation on an object in the manifests is the generation the object in the cluster
+// is expected to have reached at least, like the one a deployment has just made.
+const ExpectedGenerationAnnotation = "difftool/expected-generation"
+
+// WithGenerationCheck reports the objects in the cluster whose controllers haven't caught up as ClassProgressing,
+// which are the ones whose status.observedGeneration is older than metadata.generation,
+// or whose generation is older than ExpectedGenerationAnnotation of the local ones.
+// Objects without status.observedGeneration are only checked against the annotation.
+func WithGenerationCheck() Option {
+	return func(d *Diff) {
+		d.generationCheck = true
+	}
+}
+
+// progressing returns a ClassProgressing result telling the generations if the remote object hasn't caught up.
+func (d *Diff) progressing(local, remote *Object) []DiffResult {
+	if !d.generationCheck {
+		return nil
+	}
+	var reasons []string
+	if expected, err := strconv.ParseInt(local.Annotations[ExpectedGenerationAnnotation], 10, 64); err == nil && remote.Generation < expected {
+		reasons = append(reasons, fmt.Sprintf("generation %d is older than the expected %d", remote.Generation, expected))
+	}
+	if observed, ok := observedGeneration(remote); ok && observed < remote.Generation {
+		reasons = append(reasons, fmt.Sprintf("generation %d isn't observed yet, the observed one is %d", remote.Generation, observed))
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+	return []DiffResult{{Class: ClassProgressing, Object: remote, Diff: strings.Join(reasons, ", ") + "\n", Perspective: d.perspective, ResourceVersion: remote.ResourceVersion}}
+}
+
+// progressingIn checks the generations of the remote objects paired with the local ones in a list.
+func (d *Diff) progressingIn(local, remote []*Object) []DiffResult {
+	if !d.generationCheck {
+		return nil
+	}
+	m := make(map[string]*Object, len(local))
+	for _, o := range local {
+		m[o.String()] = o
+	}
+	var results []DiffResult
+	for _, o := range remote {
+		if l, ok := m[o.String()]; ok {
+			results = append(results, d.progressing(l, o)...)
+		}
+	}
+	return results
+}
+
+func observedGeneration(obj *Object) (int64, bool) {
+	status, _ := obj.Status.(map[string]any)
+	n, ok := numberOf(status["observedGeneration"])
+	return int64(n), ok
+}
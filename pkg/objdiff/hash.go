@@ -0,0 +1,109 @@
+package objdiff
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"math"
+	"sort"
+
+	"github.com/cockroachdb/errors"
+)
+
+// payloadHash returns the hash of the payload of the object returned by the extractor of its kind.
+// It's stable across the order of map keys, and types are hashed as well
+// since cmp tells int64(1) from float64(1).
+func payloadHash(obj *Object) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	payload, err := getExtractor(obj.GroupVersionKind().GroupKind())(obj)
+	if err != nil {
+		return sum, errors.WithStack(err)
+	}
+	h := sha256.New()
+	hashValue(h, payload)
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+func hashValue(h hash.Hash, v any) {
+	// every value is prefixed by its type so that different structures never write the same bytes
+	var buf [8]byte
+	writeLen := func(n int) {
+		binary.BigEndian.PutUint64(buf[:], uint64(n))
+		h.Write(buf[:])
+	}
+	writeString := func(s string) {
+		writeLen(len(s))
+		h.Write([]byte(s))
+	}
+	switch x := v.(type) {
+	case nil:
+		h.Write([]byte{'n'})
+	case bool:
+		if x {
+			h.Write([]byte{'t'})
+		} else {
+			h.Write([]byte{'f'})
+		}
+	case string:
+		h.Write([]byte{'s'})
+		writeString(x)
+	case BinaryValue:
+		h.Write([]byte{'b'})
+		writeString(string(x))
+	case int64:
+		h.Write([]byte{'i'})
+		binary.BigEndian.PutUint64(buf[:], uint64(x))
+		h.Write(buf[:])
+	case float64:
+		h.Write([]byte{'d'})
+		binary.BigEndian.PutUint64(buf[:], math.Float64bits(x))
+		h.Write(buf[:])
+	case []any:
+		h.Write([]byte{'a'})
+		writeLen(len(x))
+		for _, e := range x {
+			hashValue(h, e)
+		}
+	case map[string]any:
+		h.Write([]byte{'m'})
+		hashMap(h, x, writeLen, writeString)
+	case DataMap:
+		h.Write([]byte{'D'})
+		hashMap(h, x, writeLen, writeString)
+	default:
+		// payloads are decoded from json, so it's only for custom extractors
+		h.Write([]byte{'?'})
+		writeString(fmt.Sprintf("%T %#v", x, x))
+	}
+}
+
+func hashMap(h hash.Hash, m map[string]any, writeLen func(int), writeString func(string)) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	writeLen(len(keys))
+	for _, k := range keys {
+		writeString(k)
+		hashValue(h, m[k])
+	}
+}
+
+// samePayload tells if the objects can't differ whatever the options are, without comparing their structures.
+func (p Perspective) samePayload(local, remote *Object) (bool, error) {
+	if local.Kind != remote.Kind || p.diffFinalizers(local, remote) != "" {
+		return false, nil
+	}
+	lh, err := payloadHash(local)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	rh, err := payloadHash(remote)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return lh == rh, nil
+}
@@ -0,0 +1,56 @@
+package objdiff
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/cockroachdb/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+)
+
+// WithImpersonation makes the requests act as the user and the groups,
+// so that the diff shows what they can see with their RBAC.
+// Impersonation in the config given to New is used as well.
+func WithImpersonation(user string, groups ...string) Option {
+	return func(d *Diff) {
+		d.impersonate = rest.ImpersonationConfig{UserName: user, Groups: groups}
+	}
+}
+
+// labelForbidden tells who was forbidden if the request impersonated someone,
+// since it'd look like the lack of permissions of the caller otherwise.
+func (d *Diff) labelForbidden(err error) error {
+	if !kerrors.IsForbidden(err) || d.impersonate.UserName == "" {
+		return errors.WithStack(err)
+	}
+	as := d.impersonate.UserName
+	if len(d.impersonate.Groups) != 0 {
+		as += " (groups " + strings.Join(d.impersonate.Groups, ", ") + ")"
+	}
+	return errors.Wrapf(err, "forbidden while impersonating %s", as)
+}
+
+// pruneForbidden removes the items of the forbidden kinds, and returns ClassForbidden results for them
+// so that they aren't reported as missing. Items without kinds are of the kind of the mapping.
+func (d *Diff) pruneForbidden(mapping *meta.RESTMapping, items []*Object, forbidden map[schema.GroupKind]bool) ([]*Object, []DiffResult) {
+	if len(forbidden) == 0 {
+		return items, nil
+	}
+	var denied []DiffResult
+	kept := make([]*Object, 0, len(items))
+	for _, i := range items {
+		gk := i.GroupVersionKind().GroupKind()
+		if i.Kind == "" {
+			gk = mapping.GroupVersionKind.GroupKind()
+		}
+		if forbidden[gk] {
+			denied = append(denied, DiffResult{Class: ClassForbidden, Object: i, Perspective: d.perspective})
+			continue
+		}
+		kept = append(kept, i)
+	}
+	return kept, denied
+}
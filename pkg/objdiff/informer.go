@@ -0,0 +1,179 @@
+package objdiff
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// WithInformerCache serves the remote objects from informers caching the objects of the resources,
+// which are started on the first fetch of each resource in each namespace and kept up to date by watching them.
+// It trades freshness for speed when the same kinds are compared repeatedly like in Watch.
+// resync is the period the informers resync at, or 0 to never resync.
+// Subresources, the pinned versions and lists with field selectors are fetched from the cluster as usual.
+func WithInformerCache(resync time.Duration) Option {
+	return func(d *Diff) {
+		d.informerCache = true
+		d.informerResync = resync
+	}
+}
+
+// informerKey identifies an informer by the resource and the namespace it watches, which is empty for every namespace.
+type informerKey struct {
+	resource  schema.GroupVersionResource
+	namespace string
+}
+
+type cachedResource struct {
+	informer cache.SharedIndexInformer
+	stop     chan struct{}
+	stopOnce sync.Once
+	// synced is closed once the informer is synced or failed with err
+	synced chan struct{}
+	err    error
+}
+
+func (c *cachedResource) halt() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+// Refresh drops the cached objects so that they're fetched from the cluster again,
+// when the cluster is known to have changed in ways the informers may not have caught up with yet.
+func (d *Diff) Refresh() {
+	d.informersMu.Lock()
+	defer d.informersMu.Unlock()
+	for _, c := range d.informers {
+		c.halt()
+	}
+	d.informers = make(map[informerKey]*cachedResource)
+}
+
+// cachedInformer returns the synced informer of the resource in the namespace starting it if needed,
+// or false without the informer cache. The informer of every namespace serves any namespace if it's started.
+// The access is checked by listing one object first since the informer would keep retrying otherwise,
+// and the error is returned as the list would. The informer is synced out of the lock
+// so that the other resources don't wait for it, while the callers of the same one do.
+func (d *Diff) cachedInformer(resource schema.GroupVersionResource, namespace string) (cache.SharedIndexInformer, bool, error) {
+	if !d.informerCache {
+		return nil, false, nil
+	}
+	key := informerKey{resource: resource, namespace: namespace}
+	d.informersMu.Lock()
+	c, ok := d.informers[informerKey{resource: resource}]
+	if !ok {
+		c, ok = d.informers[key]
+	}
+	if !ok {
+		c = &cachedResource{stop: make(chan struct{}), synced: make(chan struct{})}
+		d.informers[key] = c
+	}
+	d.informersMu.Unlock()
+	if !ok {
+		c.err = d.startInformer(c, resource, namespace)
+		if c.err != nil {
+			c.halt()
+			d.informersMu.Lock()
+			if d.informers[key] == c {
+				// it's started again by the next fetch
+				delete(d.informers, key)
+			}
+			d.informersMu.Unlock()
+		}
+		close(c.synced)
+	}
+	<-c.synced
+	if c.err != nil {
+		return nil, true, c.err
+	}
+	return c.informer, true, nil
+}
+
+// startInformer starts the informer of the resource in the namespace and waits for it to be synced.
+func (d *Diff) startInformer(c *cachedResource, resource schema.GroupVersionResource, namespace string) error {
+	if _, err := d.client.Resource(resource).Namespace(namespace).List(context.Background(), v1.ListOptions{Limit: 1}); err != nil {
+		return errors.WithStack(err)
+	}
+	c.informer = dynamicinformer.NewFilteredDynamicInformer(d.client, resource, namespace, d.informerResync, cache.Indexers{}, nil).Informer()
+	go c.informer.Run(c.stop)
+	if !cache.WaitForCacheSync(c.stop, c.informer.HasSynced) {
+		return errors.Newf("couldn't sync the cache of %s", resource)
+	}
+	return nil
+}
+
+// getCachedObj gets the remote object from the informer cache, or returns false without it.
+func (d *Diff) getCachedObj(resource schema.GroupVersionResource, namespace, name string) (*Object, bool, error) {
+	informer, ok, err := d.cachedInformer(resource, namespace)
+	if err != nil {
+		return nil, true, d.labelForbidden(err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	key := name
+	if namespace != "" {
+		key = namespace + "/" + name
+	}
+	item, exists, err := informer.GetIndexer().GetByKey(key)
+	if err != nil {
+		return nil, true, errors.WithStack(err)
+	}
+	if !exists {
+		return nil, true, kerrors.NewNotFound(resource.GroupResource(), name)
+	}
+	obj, err := fromCached(item)
+	if err != nil {
+		return nil, true, errors.WithStack(err)
+	}
+	return obj, true, nil
+}
+
+// listCachedObjs lists the remote objects matching the label selector from the informer cache,
+// or returns false without it or if the options need the cluster.
+func (d *Diff) listCachedObjs(resource schema.GroupVersionResource, namespace string, opts v1.ListOptions) ([]*Object, bool, error) {
+	if opts.FieldSelector != "" || opts.ResourceVersion != "" {
+		return nil, false, nil
+	}
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	informer, ok, err := d.cachedInformer(resource, namespace)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	out := make([]*Object, 0)
+	for _, item := range informer.GetStore().List() {
+		obj, err := fromCached(item)
+		if err != nil {
+			return nil, true, errors.WithStack(err)
+		}
+		if namespace != "" && obj.Namespace != namespace || !selector.Matches(labels.Set(obj.Labels)) {
+			continue
+		}
+		out = append(out, obj)
+	}
+	return out, true, nil
+}
+
+// fromCached converts an object in the informer cache into an Object, which is a copy of it.
+func fromCached(item any) (*Object, error) {
+	u, ok := item.(*unstructured.Unstructured)
+	if !ok {
+		return nil, errors.Newf("unexpected object %T in the cache", item)
+	}
+	obj := new(Object)
+	if err := unmarshallUnstructured(u, obj); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return obj, nil
+}
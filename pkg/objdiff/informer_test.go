@@ -0,0 +1,55 @@
+package objdiff
+
+import (
+	"sync"
+	"testing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestCachedInformerNamespaces(t *testing.T) {
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	var objs []runtime.Object
+	for _, ns := range []string{"a", "b"} {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind("ConfigMap")
+		u.SetNamespace(ns)
+		u.SetName("settings")
+		objs = append(objs, u)
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{configMaps: "ConfigMapList"}, objs...)
+	d := &Diff{client: client, informerCache: true, informers: make(map[informerKey]*cachedResource)}
+	defer d.Refresh()
+
+	var wg sync.WaitGroup
+	listed := make([][]*Object, 4)
+	for i := range listed {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			out, ok, err := d.listCachedObjs(configMaps, "a", v1.ListOptions{})
+			if err != nil || !ok {
+				t.Errorf("ok = %v, err = %v", ok, err)
+			}
+			listed[i] = out
+		}(i)
+	}
+	wg.Wait()
+	for _, out := range listed {
+		if len(out) != 1 || out[0].Namespace != "a" {
+			t.Errorf("listed %v, want the one in a", out)
+		}
+	}
+	if len(d.informers) != 1 {
+		t.Errorf("%d informers are started, want the one of a", len(d.informers))
+	}
+	if _, ok := d.informers[informerKey{resource: configMaps, namespace: "a"}]; !ok {
+		t.Errorf("the informer isn't scoped to a: %v", d.informers)
+	}
+}
@@ -0,0 +1,47 @@
+package objdiff
+
+import (
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Inventory returns the objects in the cluster of the resource as kubectl takes like "deploy" in all namespaces,
+// which are what a list of the kind would be compared with, so that manifests can be bootstrapped from them.
+// The excluded namespaces and the objects not modified since the time are skipped as they are in lists.
+func (d *Diff) Inventory(resource string) ([]*Object, error) {
+	mapping, err := d.getResourceFromArg(resource)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	objs, err := d.getRemoteObjs(mapping.Resource, v1.ListOptions{})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	objs = d.pruneExcluded(nil, objs)
+	_, objs = d.pruneStale(nil, objs)
+	return objs, nil
+}
+
+// ListSelected returns the objects in the cluster of the kind matching the label selector in the namespace,
+// or in all namespaces if it's empty.
+func (d *Diff) ListSelected(apiVersion, kind, namespace, selector string) ([]*Object, error) {
+	mapping, err := d.getResource(apiVersion, kind)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	objs, err := d.getRemoteObjsIn(mapping.Resource, namespace, v1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return objs, nil
+}
+
+// IsNamespaced tells if the objects of the kind are namespaced.
+func (d *Diff) IsNamespaced(apiVersion, kind string) (bool, error) {
+	mapping, err := d.getResource(apiVersion, kind)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+}
@@ -0,0 +1,35 @@
+package objdiff
+
+import (
+	"sort"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/strings/slices"
+)
+
+// terminating returns a ClassTerminating result if the remote object is being deleted,
+// since such objects behave differently from the ones alive even when they don't differ.
+func (p Perspective) terminating(remote *Object) []DiffResult {
+	if remote.DeletionTimestamp == nil {
+		return nil
+	}
+	return []DiffResult{{Class: ClassTerminating, Object: remote, Perspective: p, ResourceVersion: remote.ResourceVersion}}
+}
+
+// diffFinalizers compares the finalizers regardless of the order.
+// They're compared only when the local object declares them,
+// otherwise every finalizer added by controllers would be a diff.
+func (p Perspective) diffFinalizers(local, remote *Object) string {
+	if local.Finalizers == nil {
+		return ""
+	}
+	x, y := p.order(local, remote)
+	xf, yf := slices.Clone(x.Finalizers), slices.Clone(y.Finalizers)
+	sort.Strings(xf)
+	sort.Strings(yf)
+	diff := cmp.Diff(xf, yf)
+	if diff == "" {
+		return ""
+	}
+	return "finalizers:\n" + diff
+}
@@ -0,0 +1,157 @@
+package objdiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WithFieldManager only compares the fields owned by the field manager in the managedFields of the remote objects,
+// so that the fields set by the other managers are ignored. Nothing is compared for an object the manager owns nothing of.
+func WithFieldManager(manager string) Option {
+	return func(d *Diff) {
+		d.fieldManager = manager
+	}
+}
+
+// WithManagedFields keeps metadata.managedFields in the comparison, which IgnoreManagedFields ignores by default.
+func WithManagedFields() Option {
+	return func(d *Diff) {
+		d.keepManagedFields = true
+	}
+}
+
+// IgnoreManagedFields ignores metadata.managedFields for the extractors returning the metadata,
+// whether it's typed as ObjectMeta or decoded as a map, since it always differs and dominates the diff.
+func IgnoreManagedFields() cmp.Option {
+	metaType := reflect.TypeOf(v1.ObjectMeta{})
+	return cmp.FilterPath(func(path cmp.Path) bool {
+		if sf, ok := path.Last().(cmp.StructField); ok {
+			return sf.Name() == "ManagedFields" && path.Index(-2).Type() == metaType
+		}
+		return pathKey(path) == "metadata.managedFields"
+	}, cmp.Ignore())
+}
+
+// ownedFields returns the fields owned by the manager, which are the fieldsV1 of the applies and updates merged.
+func ownedFields(obj *Object, manager string) (map[string]any, error) {
+	owned := make(map[string]any)
+	for _, e := range obj.ManagedFields {
+		if e.Manager != manager || e.FieldsV1 == nil {
+			continue
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(e.FieldsV1.Raw, &fields); err != nil {
+			return nil, errors.Wrapf(err, "couldn't parse the managedFields of %s", obj)
+		}
+		mergeFields(owned, fields)
+	}
+	return owned, nil
+}
+
+func mergeFields(dst, src map[string]any) {
+	for k, v := range src {
+		sv, _ := v.(map[string]any)
+		dv, ok := dst[k].(map[string]any)
+		if !ok {
+			dv = make(map[string]any)
+			dst[k] = dv
+		}
+		mergeFields(dv, sv)
+	}
+}
+
+// projectOwned returns copies of the objects whose payloads only have the fields owned by the manager of the remote one.
+func (d *Diff) projectOwned(local, remote *Object) (*Object, *Object, error) {
+	if d.fieldManager == "" {
+		return local, remote, nil
+	}
+	owned, err := ownedFields(remote, d.fieldManager)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	project := func(obj *Object) *Object {
+		out := *obj
+		spec, _ := owned["f:spec"].(map[string]any)
+		out.Spec = projectFields(obj.Spec, spec)
+		data, _ := owned["f:data"].(map[string]any)
+		out.Data = projectFields(obj.Data, data)
+		binaryData, _ := owned["f:binaryData"].(map[string]any)
+		out.BinaryData = projectFields(obj.BinaryData, binaryData)
+		return &out
+	}
+	return project(local), project(remote), nil
+}
+
+// projectFields returns a copy of v which only has the fields in the fieldset.
+// A field whose set is empty is owned as a whole.
+func projectFields(v any, fields map[string]any) any {
+	if fields == nil {
+		return nil
+	}
+	if len(fields) == 0 {
+		return v
+	}
+	switch x := v.(type) {
+	case map[string]any:
+		out := make(map[string]any)
+		for k, e := range x {
+			if sub, ok := fields["f:"+k].(map[string]any); ok {
+				out[k] = projectFields(e, sub)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, 0, len(x))
+		for i, e := range x {
+			if sub, ok := elementFields(fields, i, e); ok {
+				out = append(out, projectFields(e, sub))
+			}
+		}
+		return out
+	}
+	return v
+}
+
+// elementFields returns the fieldset of the element of a list,
+// which is keyed by its merge keys like `k:{"name":"app"}`, its value like `v:"a"` or its index like `i:0`.
+func elementFields(fields map[string]any, i int, e any) (map[string]any, bool) {
+	for k, v := range fields {
+		sub, _ := v.(map[string]any)
+		switch {
+		case strings.HasPrefix(k, "k:"):
+			var keys map[string]any
+			m, ok := e.(map[string]any)
+			if !ok || json.Unmarshal([]byte(k[2:]), &keys) != nil {
+				continue
+			}
+			if matchesKeys(m, keys) {
+				return sub, true
+			}
+		case strings.HasPrefix(k, "v:"):
+			raw, err := json.Marshal(e)
+			if err == nil && string(raw) == k[2:] {
+				return sub, true
+			}
+		case k == "i:"+strconv.Itoa(i):
+			return sub, true
+		}
+	}
+	return nil, false
+}
+
+func matchesKeys(m, keys map[string]any) bool {
+	for k, v := range keys {
+		x, err1 := json.Marshal(m[k])
+		y, err2 := json.Marshal(v)
+		if err1 != nil || err2 != nil || string(x) != string(y) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package objdiff
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+)
+
+// DiffObjAgainstList compares local as the manifest with its match among remote as the cluster.
+func DiffObjAgainstList(local *Object, remote []*Object, opts ...cmp.Option) ([]DiffResult, error) {
+	return PerspectiveManifest.DiffObjAgainstList(local, remote, opts...)
+}
+
+// DiffObjAgainstList finds the match of local among remote and compares them, which is useful when the remote objects
+// have generated names. The match is the object of the same identity, or the most similar object of the same kind
+// otherwise, which has the fewest differing paths preferring the same namespace.
+// It's reported as missing if there's no object of the kind.
+func (p Perspective) DiffObjAgainstList(local *Object, remote []*Object, opts ...cmp.Option) ([]DiffResult, error) {
+	match, err := p.match(local, remote, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if match == nil {
+		return []DiffResult{{Class: ClassMissing, Object: local, Perspective: p}}, nil
+	}
+	results := p.terminating(match)
+	diff, err := p.DiffObj(local, match, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if diff != "" {
+		results = append(results, DiffResult{Class: ClassChanged, Object: local, Diff: diff, Perspective: p, ResourceVersion: match.ResourceVersion})
+	}
+	if results == nil {
+		return []DiffResult{}, nil
+	}
+	return results, nil
+}
+
+// match returns the remote object matching local, or nil if there's no object of the kind.
+func (p Perspective) match(local *Object, remote []*Object, opts ...cmp.Option) (*Object, error) {
+	gk := local.GroupVersionKind().GroupKind()
+	var best *Object
+	bestScore := 0
+	for _, r := range remote {
+		if r.String() == local.String() {
+			return r, nil
+		}
+		if r.GroupVersionKind().GroupKind() != gk {
+			continue
+		}
+		diffs, err := p.DiffObjPaths(local, r, opts...)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// a differing namespace counts as much as a differing path
+		score := len(diffs)
+		if r.Namespace != local.Namespace {
+			score++
+		}
+		if best == nil || score < bestScore {
+			best, bestScore = r, score
+		}
+	}
+	return best, nil
+}
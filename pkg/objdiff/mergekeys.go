@@ -0,0 +1,138 @@
+package objdiff
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	mergeKeysMu sync.RWMutex
+	mergeKeys   = map[string][]string{
+		"containers":          {"name"},
+		"initContainers":      {"name"},
+		"ephemeralContainers": {"name"},
+		"env":                 {"name"},
+		"volumes":             {"name"},
+		"volumeMounts":        {"mountPath"},
+		// container ports and service ports share the name, and the protocol is TCP unless given
+		"ports": {"containerPort", "port", "protocol=TCP"},
+	}
+)
+
+// RegisterMergeKeys registers the keys identifying the elements of the lists at the field for MatchByMergeKeys,
+// like RegisterMergeKeys("endpoints", "name") for a list of a custom resource.
+// The field is the name of the lists anywhere in the payloads, or the key of a list with "*" for the indices
+// like "template.spec.containers.*.ports". It replaces the registered one if any, including the builtin ones.
+// A key can have the value of the elements lacking it like "protocol=TCP", which doesn't identify an element alone.
+func RegisterMergeKeys(field string, keys ...string) {
+	mergeKeysMu.Lock()
+	defer mergeKeysMu.Unlock()
+	mergeKeys[field] = keys
+}
+
+// MatchByMergeKeys compares the elements of the lists registered by RegisterMergeKeys, like containers by their names,
+// by the values of the keys rather than their indices, so that reordering or inserting an element isn't shown
+// as every following element changed. lists are the keys of more lists only for this option.
+// The elements are keyed like "name=app" in the keys of the other options, where "." and "%" in the values
+// are escaped as "%2E" and "%25" like "mountPath=/etc/app%2Econf", and lists whose elements
+// can't be told apart by the keys are compared by the indices as usual.
+func MatchByMergeKeys(lists map[string][]string) cmp.Option {
+	mergeKeysMu.RLock()
+	all := make(map[string][]string, len(mergeKeys)+len(lists))
+	for f, keys := range mergeKeys {
+		all[f] = keys
+	}
+	mergeKeysMu.RUnlock()
+	for f, keys := range lists {
+		all[f] = keys
+	}
+
+	// a list matching a key is keyed by it rather than by the name of the field
+	var patterns [][]string
+	for f := range all {
+		if strings.Contains(f, ".") {
+			patterns = append(patterns, strings.Split(f, "."))
+		}
+	}
+	matchesAny := func(key []string) bool {
+		for _, p := range patterns {
+			if matchesPattern(key, p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var opts cmp.Options
+	for f, keys := range all {
+		f, keys := f, keys
+		pattern := strings.Split(f, ".")
+		at := func(path cmp.Path) bool {
+			key := strings.Split(pathKey(path), ".")
+			if len(pattern) == 1 {
+				return key[len(key)-1] == f && !matchesAny(key)
+			}
+			return matchesPattern(key, pattern)
+		}
+		keyed := func(x, y []any) bool {
+			_, xok := keyedElements(x, keys)
+			_, yok := keyedElements(y, keys)
+			return xok && yok
+		}
+		opts = append(opts, cmp.FilterPath(at, cmp.FilterValues(keyed, cmp.Transformer("MergeKeys", func(s []any) map[string]any {
+			m, _ := keyedElements(s, keys)
+			return m
+		}))))
+	}
+	return opts
+}
+
+// keyEscaper escapes the values of the keys so that they don't split the keys of the paths.
+var keyEscaper = strings.NewReplacer("%", "%25", ".", "%2E")
+
+// keyedElements returns the elements of the list by the values of the keys,
+// or false if any element doesn't have any of them or has the same values as another.
+// The keys lacking in an element are filled with their defaults if any.
+func keyedElements(s []any, keys []string) (map[string]any, bool) {
+	out := make(map[string]any, len(s))
+	for _, e := range s {
+		m, ok := e.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		var kv []string
+		found := false
+		for _, k := range keys {
+			k, def, hasDefault := strings.Cut(k, "=")
+			v, ok := m[k]
+			found = found || ok
+			if !ok && hasDefault {
+				// the element is copied not to fill the default in the payload
+				m = withEntry(m, k, def)
+				v, ok = def, true
+			}
+			if ok {
+				kv = append(kv, k+"="+keyEscaper.Replace(fmt.Sprint(v)))
+			}
+		}
+		id := strings.Join(kv, ",")
+		if _, dup := out[id]; dup || !found {
+			return nil, false
+		}
+		out[id] = m
+	}
+	return out, true
+}
+
+// withEntry returns a copy of the map with the entry.
+func withEntry(m map[string]any, k string, v any) map[string]any {
+	out := make(map[string]any, len(m)+1)
+	for mk, mv := range m {
+		out[mk] = mv
+	}
+	out[k] = v
+	return out
+}
@@ -0,0 +1,46 @@
+package objdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMatchByMergeKeysPorts(t *testing.T) {
+	x := map[string]any{"ports": []any{
+		map[string]any{"containerPort": 53, "protocol": "UDP"},
+		map[string]any{"containerPort": 53},
+	}}
+	y := map[string]any{"ports": []any{
+		map[string]any{"containerPort": 53, "protocol": "TCP"},
+		map[string]any{"containerPort": 53, "protocol": "UDP"},
+	}}
+	if diff := cmp.Diff(x, y, MatchByMergeKeys(nil)); diff != "" {
+		t.Errorf("the ports differ:\n%s", diff)
+	}
+}
+
+func TestMatchByMergeKeysDots(t *testing.T) {
+	x := map[string]any{"volumeMounts": []any{
+		map[string]any{"mountPath": "/etc/app.conf", "name": "config", "readOnly": true},
+		map[string]any{"mountPath": "/var/log", "name": "logs"},
+	}}
+	y := map[string]any{"volumeMounts": []any{
+		map[string]any{"mountPath": "/var/log", "name": "logs"},
+		map[string]any{"mountPath": "/etc/app.conf", "name": "config", "readOnly": false},
+	}}
+	diff := cmp.Diff(x, y, MatchByMergeKeys(nil))
+	var changed []string
+	for _, l := range strings.Split(diff, "\n") {
+		if l = strings.TrimSpace(l); strings.HasPrefix(l, "-") || strings.HasPrefix(l, "+") {
+			changed = append(changed, l)
+		}
+	}
+	if !strings.Contains(diff, "mountPath=/etc/app%2Econf") || len(changed) != 2 {
+		t.Errorf("the mounts aren't matched by the paths:\n%s", diff)
+	}
+	if diff := cmp.Diff(x, y, MatchByMergeKeys(nil), IgnoreInEach("volumeMounts", "readOnly")); diff != "" {
+		t.Errorf("readOnly isn't ignored in the mounts:\n%s", diff)
+	}
+}
@@ -0,0 +1,31 @@
+package objdiff
+
+// NamespaceResolver returns the effective namespace of a local object,
+// e.g. from an annotation or the active context for manifests whose namespace is injected at apply time.
+type NamespaceResolver func(*Object) string
+
+// WithNamespaceResolver makes the local objects be compared in the namespaces the resolver returns
+// instead of their own ones.
+func WithNamespaceResolver(r NamespaceResolver) Option {
+	return func(d *Diff) {
+		d.namespaceResolver = r
+	}
+}
+
+// resolveNamespace returns a copy of obj, or of the items if it's a list, in the effective namespace.
+// obj is returned as is without a resolver.
+func (d *Diff) resolveNamespace(obj *Object) *Object {
+	if d.namespaceResolver == nil {
+		return obj
+	}
+	out := *obj
+	if !obj.IsList() {
+		out.Namespace = d.namespaceResolver(obj)
+		return &out
+	}
+	out.Items = make([]*Object, len(obj.Items))
+	for i, item := range obj.Items {
+		out.Items[i] = d.resolveNamespace(item)
+	}
+	return &out
+}
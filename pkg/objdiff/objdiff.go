@@ -3,8 +3,12 @@ package objdiff
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/google/go-cmp/cmp"
@@ -14,11 +18,17 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/managedfields"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/utils/strings/slices"
+
+	"github.com/bitoku/difftool/pkg/util"
 )
 
 type Object struct {
@@ -26,11 +36,15 @@ type Object struct {
 	v1.ObjectMeta `json:"metadata"`
 	Spec          any       `json:"spec,omitempty"`
 	Data          any       `json:"data,omitempty"`
+	BinaryData    any       `json:"binaryData,omitempty"`
+	Status        any       `json:"status,omitempty"`
 	Items         []*Object `json:"items,omitempty"`
 }
 
+// IsList tells if the object is a list by the convention that the kinds of lists end with "List",
+// so that a kind which happens to have an items field isn't taken as a list.
 func (o *Object) IsList() bool {
-	return o.Items != nil
+	return strings.HasSuffix(o.Kind, "List")
 }
 
 func (o *Object) String() string {
@@ -41,99 +55,517 @@ func (o *Object) String() string {
 }
 
 type Differ interface {
-	Diff(apiVersion, kind string, obj *Object, opts ...cmp.Option) ([]string, []string, error)
+	Diff(apiVersion, kind string, obj *Object, opts ...cmp.Option) ([]DiffResult, error)
 }
 
 type Diff struct {
-	client dynamic.Interface
-	mapper meta.RESTMapper
+	client        dynamic.Interface
+	discovery     discovery.DiscoveryInterface
+	mapper        meta.RESTMapper
+	shortcuts     meta.RESTMapper
+	resolvedMu    sync.Mutex
+	resolved      map[schema.GroupKind]schema.GroupVersionResource
+	seeded        map[schema.GroupKind]bool
+	served        map[schema.GroupVersion]map[string]bool
+	conversions   map[schema.GroupKind]conversion
+	subresources  map[schema.GroupKind]string
+	fetchVersions map[schema.GroupKind]string
+	perspective   Perspective
+	serverVersion *util.Version
+
+	modifiedSince      time.Time
+	modifiedAnnotation string
+	excludedNamespaces map[string]bool
+	namePattern        *regexp.Regexp
+	itemNamespaces     bool
+	intersectionOnly   bool
+	namespaceResolver  NamespaceResolver
+	metadata           metadata.Interface
+	partialFetch       bool
+
+	impersonate rest.ImpersonationConfig
+
+	revealSecrets  bool
+	sensitiveKinds map[schema.GroupKind]bool
+	sensitivePaths []string
+
+	fieldManager       string
+	keepManagedFields  bool
+	cleanObjects       bool
+	fields             []string
+	ignoredAnnotations []string
+	dataSummary        bool
+	maxDepth           int
+	explain            bool
+	dryRunCreate       bool
+	ignoreOwnerUIDs    bool
+	remoteObjects      bool
+	pageSize           int64
+	serial             bool
+	driftScores        bool
+	transform          *Transform
+	ignoreGenerated    bool
+	generationCheck    bool
+
+	ignoredAnnotationPrefixes []string
+
+	config          *rest.Config
+	protobuf        bool
+	protobufMu      sync.Mutex
+	protobufClients map[schema.GroupVersion]rest.Interface
+
+	informerCache  bool
+	informerResync time.Duration
+	informersMu    sync.Mutex
+	informers      map[informerKey]*cachedResource
+
+	structuredMerge bool
+	structuredOnce  sync.Once
+	structuredTypes *managedfields.GvkParser
+	structuredErr   error
+
+	fetchedCount atomic.Int64
+	diffedCount  atomic.Int64
+
+	pinned     map[string]string
+	observedMu sync.Mutex
+	observed   map[string]string
 }
 
-func New(config *rest.Config) (*Diff, error) {
-	mapper, err := getRESTMapper(config)
+// Option configures a Diff.
+type Option func(*Diff)
+
+func New(config *rest.Config, options ...Option) (*Diff, error) {
+	d := &Diff{
+		resolved:      make(map[schema.GroupKind]schema.GroupVersionResource),
+		seeded:        make(map[schema.GroupKind]bool),
+		served:        make(map[schema.GroupVersion]map[string]bool),
+		conversions:   make(map[schema.GroupKind]conversion),
+		subresources:  make(map[schema.GroupKind]string),
+		fetchVersions: make(map[schema.GroupKind]string),
+		perspective:   PerspectiveManifest,
+
+		excludedNamespaces: make(map[string]bool),
+
+		observed: make(map[string]string),
+
+		sensitiveKinds: map[schema.GroupKind]bool{{Kind: "Secret"}: true},
+
+		ignoredAnnotations:        DefaultIgnoredAnnotations,
+		ignoredAnnotationPrefixes: DefaultIgnoredAnnotationPrefixes,
+
+		protobufClients: make(map[schema.GroupVersion]rest.Interface),
+		informers:       make(map[informerKey]*cachedResource),
+	}
+	for _, o := range options {
+		o(d)
+	}
+
+	// options are applied first since impersonation has to be configured before the clients are built
+	config = rest.CopyConfig(config)
+	if d.impersonate.UserName != "" {
+		config.Impersonate = d.impersonate
+	}
+	d.impersonate = config.Impersonate
+	d.config = config
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	mapper := getRESTMapper(discoveryClient)
 
 	client, err := dynamic.NewForConfig(config)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	return &Diff{client: client, mapper: mapper}, nil
+	metadataClient, err := metadata.NewForConfig(config)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	d.client = client
+	d.metadata = metadataClient
+	d.discovery = discoveryClient
+	d.mapper = mapper
+	d.shortcuts = restmapper.NewShortcutExpander(mapper, discoveryClient)
+	return d, nil
 }
 
-func (d *Diff) Diff(apiVersion, kind string, obj *Object, opts ...cmp.Option) ([]string, []string, error) {
-	resource, err := d.getResource(apiVersion, kind)
+// NewFromKubeconfig is New with the config of the context in the kubeconfig, or the current context if it's empty.
+func NewFromKubeconfig(kubeconfig, context string, options ...Option) (*Diff, error) {
+	config, err := LoadConfig(kubeconfig, context)
 	if err != nil {
-		return nil, nil, errors.WithStack(err)
+		return nil, errors.WithStack(err)
+	}
+	return New(config, options...)
+}
+
+// LoadConfig loads the config of the context in the kubeconfig, or the current context if it's empty.
+func LoadConfig(kubeconfig, context string) (*rest.Config, error) {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
+		&clientcmd.ConfigOverrides{CurrentContext: context},
+	).ClientConfig()
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't load the context %q", context)
+	}
+	return config, nil
+}
+
+func (d *Diff) Diff(apiVersion, kind string, obj *Object, opts ...cmp.Option) ([]DiffResult, error) {
+	mapping, err := d.getResource(apiVersion, kind)
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
 
+	obj = d.resolveNamespace(obj)
+	var results []DiffResult
 	if obj.IsList() {
-		return d.diffList(resource, obj, opts...)
+		results, err = d.diffList(mapping, obj.Items, opts...)
+		d.diffedCount.Add(int64(len(obj.Items)))
+	} else if isSkipped(obj) {
+		results = []DiffResult{{Class: ClassSkipped, Object: obj, Perspective: d.perspective}}
+	} else {
+		obj, err = d.selectOne(mapping.Resource, obj)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		var converted *Object
+		converted, err = d.convert(obj, mapping.GroupVersionKind)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		results, err = d.diffObj(mapping.Resource, converted, opts...)
+		d.diffedCount.Add(1)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
-	return d.diffObj(resource, obj, opts...)
+	results = d.pruneOneSided(results)
+	results, err = d.dryRunMissing(results)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	d.markRedacted(results)
+	return results, nil
 }
 
-func (d *Diff) diffObj(resource schema.GroupVersionResource, obj *Object, opts ...cmp.Option) ([]string, []string, error) {
+func (d *Diff) diffObj(resource schema.GroupVersionResource, obj *Object, opts ...cmp.Option) ([]DiffResult, error) {
 	remote, err := d.getRemoteObj(resource, obj)
 	if kerrors.IsNotFound(errors.Cause(err)) {
-		return []string{fmt.Sprintf("- %s is not found\n", obj)}, []string{}, nil
+		return []DiffResult{{Class: ClassMissing, Object: obj, Perspective: d.perspective}}, nil
+	}
+	if kerrors.IsForbidden(errors.Cause(err)) {
+		return []DiffResult{{Class: ClassForbidden, Object: obj, Perspective: d.perspective}}, nil
 	}
 	if err != nil {
-		return nil, nil, errors.WithStack(err)
+		return nil, errors.WithStack(err)
+	}
+	if d.isStale(remote) {
+		return []DiffResult{}, nil
+	}
+	if d.subresourcesOf(obj) != nil {
+		obj = projectSpec(obj, remote)
+	}
+	results := append(d.perspective.terminating(remote), d.progressing(obj, remote)...)
+	// the result keeps the whole object while only the comparable copies are compared
+	x, y, err := d.comparable(obj, remote)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	diff, err := d.diffPair(x, y, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	diff, err = d.explained(diff, x, y, remote, opts)
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
-	diff := DiffObj(obj, remote, opts...)
 	if diff != "" {
-		return []string{}, []string{diff}, nil
+		results = append(results, DiffResult{Class: ClassChanged, Object: obj, Diff: diff, Perspective: d.perspective, ResourceVersion: remote.ResourceVersion})
+		d.attachRemote(results, []*Object{remote})
+		if err := d.scoreDrift(results, []*Object{remote}, opts); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if results == nil {
+		return []DiffResult{}, nil
+	}
+	return results, nil
+}
+
+// diffList compares the items with the remote objects of the mapping,
+// and of every other kind among the items so that a list can contain different kinds.
+// The remote objects are compared page by page as they're listed, pairing them with the items across the pages.
+func (d *Diff) diffList(mapping *meta.RESTMapping, items []*Object, opts ...cmp.Option) ([]DiffResult, error) {
+	items, skipped := d.pruneSkipped(items)
+	mappings, items, err := d.listMappings(mapping, items)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// the items matching no names are pruned regardless of the remote objects
+	kept, _ := d.pruneNames(items, nil)
+	matcher := d.perspective.newListMatcher(kept, d.samePayload, func(local, remote *Object) (string, error) {
+		x, y, err := d.comparable(local, remote)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		diff, err := d.diffPair(x, y, opts...)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		return d.explained(diff, x, y, remote, opts)
+	})
+	var results []DiffResult
+	var forbidden map[schema.GroupKind]bool
+	err = d.comparePages(func(emit func([]*Object) error) error {
+		var err error
+		forbidden, err = d.streamRemote(mappings, items, emit)
+		return err
+	}, func(remote []*Object) error {
+		remote = pruneSkippedRemote(remote, skipped)
+		remote = d.pruneExcluded(items, remote)
+		remote, stale := d.splitStale(remote)
+		matcher.drop(stale)
+		_, remote = d.pruneNames(nil, remote)
+		matched, err := matcher.match(remote)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		d.attachRemote(matched, remote)
+		if err := d.scoreDrift(matched, remote, opts); err != nil {
+			return errors.WithStack(err)
+		}
+		results = append(results, matched...)
+		results = append(results, d.progressingIn(kept, remote)...)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
-	return []string{}, []string{}, nil
+	results = append(results, matcher.missing()...)
+	_, denied := d.pruneForbidden(mapping, items, forbidden)
+	results = pruneForbiddenResults(mapping, results, forbidden)
+	return append(append(results, denied...), skipped...), nil
 }
 
-func (d *Diff) diffList(resource schema.GroupVersionResource, obj *Object, opts ...cmp.Option) ([]string, []string, error) {
-	remote, err := d.getRemoteObjs(resource)
+// pruneForbiddenResults removes the results of the forbidden kinds, which are replaced with ClassForbidden ones
+// even if some of their objects are listed before the access is denied.
+func pruneForbiddenResults(mapping *meta.RESTMapping, results []DiffResult, forbidden map[schema.GroupKind]bool) []DiffResult {
+	if len(forbidden) == 0 {
+		return results
+	}
+	kept := make([]DiffResult, 0, len(results))
+	for _, r := range results {
+		gk := r.Object.GroupVersionKind().GroupKind()
+		if r.Object.Kind == "" {
+			gk = mapping.GroupVersionKind.GroupKind()
+		}
+		if !forbidden[gk] {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// listRemote lists the remote objects of the mappings which the items are compared with.
+// The kinds which can't be listed since the access is denied are returned instead of failing.
+func (d *Diff) listRemote(mappings []*meta.RESTMapping, items []*Object) ([]*Object, map[schema.GroupKind]bool, error) {
+	var remote []*Object
+	forbidden, err := d.streamRemote(mappings, items, func(page []*Object) error {
+		remote = append(remote, page...)
+		return nil
+	})
 	if err != nil {
 		return nil, nil, errors.WithStack(err)
 	}
-	presences, diffs := DiffList(obj.Items, remote, opts...)
-	return presences, diffs, nil
+	if len(forbidden) == 0 {
+		return remote, forbidden, nil
+	}
+	kept := make([]*Object, 0, len(remote))
+	for _, o := range remote {
+		if !forbidden[o.GroupVersionKind().GroupKind()] {
+			kept = append(kept, o)
+		}
+	}
+	return kept, forbidden, nil
 }
 
-func (d *Diff) getRemoteObjs(resource schema.GroupVersionResource) ([]*Object, error) {
-	resp, err := d.client.
-		Resource(resource).
-		List(context.Background(), v1.ListOptions{})
+// streamRemote lists the remote objects of the mappings which the items are compared with, passing them to emit
+// page by page. The kinds which can't be listed since the access is denied are returned instead of failing,
+// and the objects of them passed before the access is denied are up to the caller to discard.
+func (d *Diff) streamRemote(mappings []*meta.RESTMapping, items []*Object, emit func([]*Object) error) (map[schema.GroupKind]bool, error) {
+	listOpts := d.listOptions(items)
+	forbidden := make(map[schema.GroupKind]bool)
+	for i, m := range mappings {
+		for _, ns := range d.listNamespaces(m, i == 0, items) {
+			err := d.streamRemoteObjs(m, ns, listOpts, emit)
+			if kerrors.IsForbidden(errors.Cause(err)) {
+				// the other kinds are still compared
+				forbidden[m.GroupVersionKind.GroupKind()] = true
+				break
+			}
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+	}
+	return forbidden, nil
+}
+
+// listMappings returns the mappings of the kinds among the items in addition to the given one,
+// and the items converted into the served versions.
+func (d *Diff) listMappings(mapping *meta.RESTMapping, items []*Object) ([]*meta.RESTMapping, []*Object, error) {
+	mappings := []*meta.RESTMapping{mapping}
+	listed := map[schema.GroupVersionResource]bool{mapping.Resource: true}
+	converted := make([]*Object, 0, len(items))
+	for _, i := range items {
+		m := mapping
+		if i.Kind != "" && i.GroupVersionKind().GroupKind() != mapping.GroupVersionKind.GroupKind() {
+			var err error
+			m, err = d.resolve(i.GroupVersionKind())
+			if err != nil {
+				return nil, nil, errors.WithStack(err)
+			}
+			if !listed[m.Resource] {
+				listed[m.Resource] = true
+				mappings = append(mappings, m)
+			}
+		}
+		c, err := d.convert(i, m.GroupVersionKind)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		converted = append(converted, c)
+	}
+	return mappings, converted, nil
+}
+
+func (d *Diff) getRemoteObjs(resource schema.GroupVersionResource, opts v1.ListOptions) ([]*Object, error) {
+	return d.getRemoteObjsIn(resource, "", opts)
+}
+
+// getRemoteObjsIn lists the remote objects in the namespace, or in all namespaces if it's empty.
+func (d *Diff) getRemoteObjsIn(resource schema.GroupVersionResource, namespace string, opts v1.ListOptions) ([]*Object, error) {
+	out := make([]*Object, 0)
+	err := d.streamRemoteObjsIn(resource, namespace, opts, func(page []*Object) error {
+		out = append(out, page...)
+		return nil
+	})
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	return out, nil
+}
 
-	out := make([]*Object, 0)
-	for _, i := range resp.Items {
-		newObj := new(Object)
-		err = unmarshallUnstructured(&i, newObj)
+// streamRemoteObjsIn lists the remote objects in the namespace, or in all namespaces if it's empty,
+// passing them to emit page by page with WithPageSize, otherwise all at once.
+func (d *Diff) streamRemoteObjsIn(resource schema.GroupVersionResource, namespace string, opts v1.ListOptions, emit func([]*Object) error) error {
+	emitted := false
+	emitPage := func(page []*Object) error {
+		page, err := d.pin(resource, page)
 		if err != nil {
-			return nil, errors.WithStack(err)
+			return errors.WithStack(err)
 		}
-		out = append(out, newObj)
+		d.fetchedCount.Add(int64(len(page)))
+		d.observe(page...)
+		emitted = true
+		return emit(page)
 	}
-	return out, nil
+	out, ok, err := d.listCachedObjs(resource, namespace, opts)
+	if ok && err == nil {
+		err = emitPage(out)
+	}
+	if !ok && err == nil {
+		ok, err = d.streamProtobufObjs(resource, namespace, opts, emitPage)
+	}
+	if !ok && err == nil {
+		err = d.streamJSONObjs(resource, namespace, opts, emitPage)
+	}
+	if kerrors.IsBadRequest(err) && opts.FieldSelector != "" && !emitted {
+		// some resources don't support the field selector, and the objects are filtered locally instead
+		opts.FieldSelector = ""
+		return d.streamRemoteObjsIn(resource, namespace, opts, emit)
+	}
+	if err != nil {
+		return d.labelForbidden(err)
+	}
+	return nil
+}
+
+// streamJSONObjs lists the remote objects with the dynamic client page by page,
+// returning the error of the request as is.
+func (d *Diff) streamJSONObjs(resource schema.GroupVersionResource, namespace string, opts v1.ListOptions, emit func([]*Object) error) error {
+	return d.listPages(opts, func(opts v1.ListOptions) ([]*Object, string, error) {
+		resp, err := d.client.
+			Resource(resource).
+			Namespace(namespace).
+			List(context.Background(), opts)
+		if err != nil {
+			return nil, "", err
+		}
+
+		page := make([]*Object, 0, len(resp.Items))
+		for _, i := range resp.Items {
+			newObj := new(Object)
+			err = unmarshallUnstructured(&i, newObj)
+			if err != nil {
+				return nil, "", errors.WithStack(err)
+			}
+			page = append(page, newObj)
+		}
+		return page, resp.GetContinue(), nil
+	}, emit)
 }
 
 func (d *Diff) getRemoteObj(resource schema.GroupVersionResource, obj *Object) (*Object, error) {
 	var resp *unstructured.Unstructured
 	var err error
+	subresources := d.subresourcesOf(obj)
+	if rv, ok := d.pinned[obj.String()]; ok && subresources == nil {
+		pinned, err := d.getPinnedObj(resource, obj.Namespace, obj.Name, rv)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		d.fetchedCount.Add(1)
+		d.observe(pinned)
+		return pinned, nil
+	}
+	if subresources == nil {
+		cachedObj, ok, err := d.getCachedObj(resource, obj.Namespace, obj.Name)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if ok {
+			d.fetchedCount.Add(1)
+			d.observe(cachedObj)
+			return cachedObj, nil
+		}
+		protobufObj, ok, err := d.getProtobufObj(resource, obj.Namespace, obj.Name)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if ok {
+			d.fetchedCount.Add(1)
+			d.observe(protobufObj)
+			return protobufObj, nil
+		}
+	}
 	if obj.Namespace != "" {
 		resp, err = d.client.
 			Resource(resource).
 			Namespace(obj.Namespace).
-			Get(context.Background(), obj.Name, v1.GetOptions{})
+			Get(context.Background(), obj.Name, v1.GetOptions{}, subresources...)
 	} else {
 		resp, err = d.client.
 			Resource(resource).
-			Get(context.Background(), obj.Name, v1.GetOptions{})
+			Get(context.Background(), obj.Name, v1.GetOptions{}, subresources...)
 	}
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, d.labelForbidden(err)
 	}
 
 	newObj := new(Object)
@@ -141,51 +573,62 @@ func (d *Diff) getRemoteObj(resource schema.GroupVersionResource, obj *Object) (
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	d.fetchedCount.Add(1)
+	d.observe(newObj)
 	return newObj, nil
 }
 
-func (d *Diff) getResource(apiVersion, kind string) (schema.GroupVersionResource, error) {
+func (d *Diff) getResource(apiVersion, kind string) (*meta.RESTMapping, error) {
 	gv, err := schema.ParseGroupVersion(apiVersion)
 	if err != nil {
-		return schema.GroupVersionResource{}, errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
+	return d.resolve(gv.WithKind(kind))
+}
 
-	gvk := gv.WithKind(kind)
-	mapping, err := d.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
-	if err != nil {
-		return schema.GroupVersionResource{}, errors.WithStack(err)
-	}
-	return mapping.Resource, nil
+// pathKey returns the keys of the path joined with dots like "spec.containers.0.name".
+func pathKey(path cmp.Path) string {
+	return strings.Join(pathKeys(path), ".")
 }
 
-func IgnoreMapEntries(ignoredKeys []string) cmp.Option {
-	filter := func(path cmp.Path) bool {
-		var key []string
-		for _, ps := range path {
-			switch x := ps.(type) {
-			case cmp.MapIndex:
-				key = append(key, x.Key().String())
-			case cmp.SliceIndex:
-				key = append(key, strconv.Itoa(x.Key()))
+// pathKeys returns the keys of the path, where the indices of lists are in decimal.
+func pathKeys(path cmp.Path) []string {
+	var key []string
+	for _, ps := range path {
+		switch x := ps.(type) {
+		case cmp.MapIndex:
+			key = append(key, x.Key().String())
+		case cmp.SliceIndex:
+			i := x.Key()
+			// the index is -1 if the element only exists on one side
+			if ix, iy := x.SplitKeys(); i == -1 && ix != -1 {
+				i = ix
+			} else if i == -1 {
+				i = iy
 			}
+			key = append(key, strconv.Itoa(i))
 		}
-		// check it naively since ignoredKeys won't be so long,
-		return slices.Contains(ignoredKeys, strings.Join(key, "."))
 	}
-	return cmp.FilterPath(filter, cmp.Ignore())
+	return key
 }
 
-func getRESTMapper(config *rest.Config) (meta.RESTMapper, error) {
-	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
-	if err != nil {
-		return nil, errors.WithStack(err)
-	}
-	groupResources, err := restmapper.GetAPIGroupResources(discoveryClient)
-	if err != nil {
-		return nil, errors.WithStack(err)
+// atPaths returns a filter which matches the given keys.
+func atPaths(keys []string) func(cmp.Path) bool {
+	return func(path cmp.Path) bool {
+		// check it naively since keys won't be so long,
+		return slices.Contains(keys, pathKey(path))
 	}
-	mapper := restmapper.NewDiscoveryRESTMapper(groupResources)
-	return mapper, nil
+}
+
+func IgnoreMapEntries(ignoredKeys []string) cmp.Option {
+	return cmp.FilterPath(atPaths(ignoredKeys), cmp.Ignore())
+}
+
+// getRESTMapper returns a mapper which discovers the resources on the first lookup,
+// so that nothing is discovered if every kind is in the resolved resources.
+// It discovers them again when a kind isn't found in case it's been installed since then.
+func getRESTMapper(discoveryClient discovery.DiscoveryInterface) meta.RESTMapper {
+	return restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
 }
 
 func unmarshallUnstructured(u *unstructured.Unstructured, v any) error {
@@ -196,37 +639,121 @@ func unmarshallUnstructured(u *unstructured.Unstructured, v any) error {
 	return json.Unmarshal(rawJson, v)
 }
 
-func DiffObj(obj1, obj2 *Object, opts ...cmp.Option) string {
-	if obj1.Kind == "ConfigMap" {
-		return cmp.Diff(obj1.Data, obj2.Data, opts...)
+// DiffObj compares obj1 as the manifest with obj2 as the cluster.
+func DiffObj(obj1, obj2 *Object, opts ...cmp.Option) (string, error) {
+	return PerspectiveManifest.DiffObj(obj1, obj2, opts...)
+}
+
+// DiffList compares obj1 as the manifests with obj2 as the cluster.
+func DiffList(obj1, obj2 []*Object, opts ...cmp.Option) ([]DiffResult, error) {
+	return PerspectiveManifest.DiffList(obj1, obj2, opts...)
+}
+
+// DiffObj compares the payloads of the objects returned by the extractor registered for the kind.
+func (p Perspective) DiffObj(local, remote *Object, opts ...cmp.Option) (string, error) {
+	x, y := p.order(local, remote)
+	xp, yp, err := extract(x, y)
+	if err != nil {
+		return "", errors.WithStack(err)
 	}
-	return cmp.Diff(obj1.Spec, obj2.Spec, opts...)
+	return cmp.Diff(xp, yp, withIgnoredKeys(local, withComparers(local, opts))...) + p.diffFinalizers(local, remote), nil
+}
+
+type listEntry struct {
+	obj     *Object
+	checked bool
+}
+
+func (p Perspective) DiffList(local, remote []*Object, opts ...cmp.Option) ([]DiffResult, error) {
+	return p.diffList(local, remote, p.samePayload, func(local, remote *Object) (string, error) {
+		return p.DiffObj(local, remote, opts...)
+	})
 }
 
-func DiffList(obj1, obj2 []*Object, opts ...cmp.Option) (presences, diffs []string) {
-	m := make(map[string]*Object)
-	checked := make(map[string]bool)
-	for _, o := range obj1 {
-		m[o.String()] = o
-		checked[o.String()] = false
+// diffList matches the local and remote objects by their identities and compares the pairs with diffObj
+// unless same tells they can't differ.
+func (p Perspective) diffList(local, remote []*Object, same func(local, remote *Object) (bool, error), diffObj func(local, remote *Object) (string, error)) ([]DiffResult, error) {
+	m := p.newListMatcher(local, same, diffObj)
+	results, err := m.match(remote)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return append(results, m.missing()...), nil
+}
+
+// listMatcher matches the remote objects with the local ones as they come, so that a list can be compared
+// page by page, pairing the objects across the pages. The local objects never matched are missing.
+type listMatcher struct {
+	p       Perspective
+	local   []*Object
+	keys    []string
+	m       map[string]listEntry
+	same    func(local, remote *Object) (bool, error)
+	diffObj func(local, remote *Object) (string, error)
+}
+
+func (p Perspective) newListMatcher(local []*Object, same func(local, remote *Object) (bool, error), diffObj func(local, remote *Object) (string, error)) *listMatcher {
+	m := &listMatcher{p: p, local: local, keys: make([]string, len(local)), m: make(map[string]listEntry, len(local)), same: same, diffObj: diffObj}
+	for i, o := range local {
+		m.keys[i] = o.String()
+		m.m[m.keys[i]] = listEntry{obj: o}
 	}
-	for _, o2 := range obj2 {
-		o1, ok := m[o2.String()]
+	return m
+}
+
+// match compares the remote objects with the local ones of the same identities, and reports the others as extra.
+func (m *listMatcher) match(remote []*Object) ([]DiffResult, error) {
+	var results []DiffResult
+	for _, o2 := range remote {
+		key := o2.String()
+		e, ok := m.m[key]
 		if !ok {
-			presences = append(presences, fmt.Sprintf("- %s is not found\n", o2))
+			results = append(results, DiffResult{Class: ClassExtra, Object: o2, Perspective: m.p, ResourceVersion: o2.ResourceVersion})
 			continue
 		}
-		checked[o2.String()] = true
-		diff := DiffObj(o1, o2, opts...)
+		e.checked = true
+		m.m[key] = e
+		results = append(results, m.p.terminating(o2)...)
+		// identical payloads have no diff, so hashing them is enough for most of the objects in large lists
+		skip, err := m.same(e.obj, o2)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if skip {
+			continue
+		}
+		diff, err := m.diffObj(e.obj, o2)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
 		if diff == "" {
 			continue
 		}
-		diffs = append(diffs, fmt.Sprintf("%s\n%s", o2.String(), diff))
+		results = append(results, DiffResult{Class: ClassChanged, Object: e.obj, Diff: diff, Perspective: m.p, ResourceVersion: o2.ResourceVersion})
 	}
-	for k, v := range m {
-		if !checked[k] {
-			presences = append(presences, fmt.Sprintf("+ %s is found, but not in default\n", v))
+	return results, nil
+}
+
+// drop marks the local objects of the identities of the remote objects as matched without comparing them,
+// so that the objects skipped remotely aren't reported as missing.
+func (m *listMatcher) drop(remote []*Object) {
+	for _, o := range remote {
+		if e, ok := m.m[o.String()]; ok {
+			e.checked = true
+			m.m[o.String()] = e
+		}
+	}
+}
+
+// missing reports the local objects which no remote objects have matched.
+func (m *listMatcher) missing() []DiffResult {
+	var results []DiffResult
+	// iterate over local rather than the map to keep the order stable
+	for i, o := range m.local {
+		e := m.m[m.keys[i]]
+		if e.obj == o && !e.checked {
+			results = append(results, DiffResult{Class: ClassMissing, Object: o, Perspective: m.p})
 		}
 	}
-	return presences, diffs
+	return results
 }
@@ -0,0 +1,288 @@
+package objdiff
+
+import (
+	"encoding/base64"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/utils/strings/slices"
+)
+
+// EquateCoercedScalars treats scalars of different types as equal at the given keys
+// if they're the same value once coerced, e.g. "3" and 3, or "true" and true.
+// It's scoped to the keys so that genuine type errors elsewhere are still reported.
+func EquateCoercedScalars(keys ...string) cmp.Option {
+	differentScalars := func(x, y any) bool {
+		_, okx := scalarString(x)
+		_, oky := scalarString(y)
+		return okx && oky && reflect.TypeOf(x) != reflect.TypeOf(y)
+	}
+	return cmp.FilterPath(atPaths(keys), cmp.FilterValues(differentScalars, cmp.Comparer(coercedEqual)))
+}
+
+// EquateBooleanStrings treats "true" and true, and "false" and false as equal at the given keys,
+// like the booleans of hand-written annotations or custom resources normalized by the server.
+// It's narrower than EquateCoercedScalars since numbers or other strings are still reported.
+func EquateBooleanStrings(keys ...string) cmp.Option {
+	booleanAndString := func(x, y any) bool {
+		_, okx := x.(bool)
+		_, oky := y.(string)
+		if okx && oky {
+			return true
+		}
+		_, okx = x.(string)
+		_, oky = y.(bool)
+		return okx && oky
+	}
+	return cmp.FilterPath(atPaths(keys), cmp.FilterValues(booleanAndString, cmp.Comparer(coercedEqual)))
+}
+
+func coercedEqual(x, y any) bool {
+	sx, _ := scalarString(x)
+	sy, _ := scalarString(y)
+	if sx == sy {
+		return true
+	}
+	// numbers may be formatted differently like "3.0" and 3
+	fx, errx := strconv.ParseFloat(sx, 64)
+	fy, erry := strconv.ParseFloat(sy, 64)
+	return errx == nil && erry == nil && fx == fy
+}
+
+// scalarString formats the scalar values decoded from json.
+func scalarString(v any) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case bool:
+		return strconv.FormatBool(x), true
+	case int64:
+		return strconv.FormatInt(x, 10), true
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// EquateEmbeddedJSON compares the values of ConfigMap and Secret data keys as json documents
+// if both of them are json, so that formatting like whitespaces or the order of keys doesn't matter.
+// Values of Secrets are base64 decoded before parsed.
+func EquateEmbeddedJSON() cmp.Option {
+	// the path is like root -> key -> type assertion to string
+	filter := func(path cmp.Path) bool {
+		return len(path) == 3 && isDataValue(path)
+	}
+	bothJSON := func(x, y string) bool {
+		_, okx := parseEmbeddedJSON(x)
+		_, oky := parseEmbeddedJSON(y)
+		return okx && oky
+	}
+	transform := cmp.Transformer("ParseJSON", func(s string) any {
+		v, _ := parseEmbeddedJSON(s)
+		return v
+	})
+	return cmp.FilterPath(filter, cmp.FilterValues(bothJSON, transform))
+}
+
+func parseEmbeddedJSON(s string) (any, bool) {
+	if v, ok := parseJSONDocument(s); ok {
+		return v, true
+	}
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+	return parseJSONDocument(string(decoded))
+}
+
+// parseJSONDocument parses s if it's a json object or array, which doesn't include bare scalars like "1".
+func parseJSONDocument(s string) (any, bool) {
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return nil, false
+	}
+	var v any
+	if err := json.Unmarshal([]byte(trimmed), &v); err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// EquateEmbeddedYAML compares the values of the given ConfigMap and Secret data keys as yaml documents
+// if both of them can be parsed, so that indentation or the order of keys doesn't matter.
+// Values which can't be parsed are compared as strings.
+func EquateEmbeddedYAML(keys ...string) cmp.Option {
+	filter := func(path cmp.Path) bool {
+		return len(path) == 3 && isDataValue(path) && slices.Contains(keys, pathKey(path))
+	}
+	bothYAML := func(x, y string) bool {
+		_, okx := parseEmbeddedYAML(x)
+		_, oky := parseEmbeddedYAML(y)
+		return okx && oky
+	}
+	transform := cmp.Transformer("ParseYAML", func(s string) any {
+		v, _ := parseEmbeddedYAML(s)
+		return v
+	})
+	return cmp.FilterPath(filter, cmp.FilterValues(bothYAML, transform))
+}
+
+func parseEmbeddedYAML(s string) (any, bool) {
+	// convert yaml to json first as loading manifests does, so that int64 isn't inferred as float64
+	jsonContent, err := yaml.ToJSON([]byte(s))
+	if err != nil {
+		return nil, false
+	}
+	var v any
+	if err := json.Unmarshal(jsonContent, &v); err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// CompareDataKeysOnly compares only the sets of the keys of ConfigMap and Secret data,
+// so that keys added or removed are reported while values differing aren't.
+// Values are never shown in the diff either, which makes it safe for secrets in logs.
+// It doesn't affect kinds without data.
+func CompareDataKeysOnly() cmp.Option {
+	return cmp.Transformer("DataKeys", func(m DataMap) []string {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys
+	})
+}
+
+// OptionalFields treats a field at the given keys absent on one side as equal to a zero value on the other,
+// like "" or 0 or false or an empty map or slice, since it's usually omitted because it's the default.
+// It's scoped to the keys so that fields disappearing elsewhere are still reported.
+func OptionalFields(keys ...string) cmp.Option {
+	filter := func(path cmp.Path) bool {
+		if !slices.Contains(keys, pathKey(path)) {
+			return false
+		}
+		vx, vy := path.Last().Values()
+		switch {
+		case !vx.IsValid() && vy.IsValid():
+			return isZeroValue(vy.Interface())
+		case vx.IsValid() && !vy.IsValid():
+			return isZeroValue(vx.Interface())
+		}
+		return false
+	}
+	return cmp.FilterPath(filter, cmp.Ignore())
+}
+
+func isZeroValue(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case string:
+		return x == ""
+	case bool:
+		return !x
+	case int64:
+		return x == 0
+	case float64:
+		return x == 0
+	case map[string]any:
+		return len(x) == 0
+	case []any:
+		return len(x) == 0
+	}
+	return false
+}
+
+// IgnoreValuesMatching ignores the difference of the strings at the key if both of them match the pattern,
+// such as generated hostnames or embedded timestamps, while it's still reported if either doesn't match.
+func IgnoreValuesMatching(key string, pattern *regexp.Regexp) cmp.Option {
+	bothMatch := func(x, y string) bool {
+		return pattern.MatchString(x) && pattern.MatchString(y)
+	}
+	return cmp.FilterPath(atPaths([]string{key}), cmp.FilterValues(bothMatch, cmp.Comparer(func(x, y string) bool { return true })))
+}
+
+// EquateNumbersWithin treats numbers at the keys as equal if they're within the tolerance like cmpopts.EquateApprox,
+// which is the larger of the fraction of the smaller magnitude and the margin,
+// so that fields controllers keep adjusting like replicas don't flap.
+// It's scoped to the keys so that it never masks changes elsewhere.
+func EquateNumbersWithin(fraction, margin float64, keys ...string) cmp.Option {
+	bothNumbers := func(x, y any) bool {
+		_, okx := numberOf(x)
+		_, oky := numberOf(y)
+		return okx && oky
+	}
+	within := func(x, y any) bool {
+		fx, _ := numberOf(x)
+		fy, _ := numberOf(y)
+		delta := math.Abs(fx - fy)
+		return delta <= margin || delta <= fraction*math.Min(math.Abs(fx), math.Abs(fy))
+	}
+	return cmp.FilterPath(atPaths(keys), cmp.FilterValues(bothNumbers, cmp.Comparer(within)))
+}
+
+func numberOf(v any) (float64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
+
+// DiffDataLines compares multi-line values of ConfigMap and Secret data line by line,
+// so that a change of a line in a file is shown as the line rather than the whole file replaced.
+// It can't be combined with EquateEmbeddedJSON or EquateEmbeddedYAML since they'd transform the same values.
+func DiffDataLines() cmp.Option {
+	// the path is like root -> key -> type assertion to string
+	filter := func(path cmp.Path) bool {
+		return len(path) == 3 && isDataValue(path)
+	}
+	multiLine := func(x, y string) bool {
+		return strings.Contains(x, "\n") || strings.Contains(y, "\n")
+	}
+	return cmp.FilterPath(filter, cmp.FilterValues(multiLine, cmp.Transformer("Lines", func(s string) []string {
+		return strings.Split(s, "\n")
+	})))
+}
+
+// IgnoreInEach ignores the field in every element of the slice at the key regardless of the index,
+// like IgnoreInEach("template.spec.containers", "imagePullPolicy").
+// The slice and the field may have "*" for the elements of nested slices like "env.*.value".
+func IgnoreInEach(slice, field string) cmp.Option {
+	pattern := append(strings.Split(slice, "."), "*")
+	pattern = append(pattern, strings.Split(field, ".")...)
+	return cmp.FilterPath(func(path cmp.Path) bool {
+		return matchesPattern(strings.Split(pathKey(path), "."), pattern)
+	}, cmp.Ignore())
+}
+
+// matchesPattern tells if the keys of a path match the pattern, where "*" matches any index of a slice
+// or any element keyed by MatchByMergeKeys.
+func matchesPattern(keys, pattern []string) bool {
+	if len(keys) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if p == "*" {
+			if _, err := strconv.Atoi(keys[i]); err != nil && !strings.Contains(keys[i], "=") {
+				return false
+			}
+			continue
+		}
+		if keys[i] != p {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package objdiff
+
+import (
+	"testing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func deployment(spec map[string]any) *Object {
+	return &Object{
+		TypeMeta:   v1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec:       spec,
+	}
+}
+
+func TestOptionalFields(t *testing.T) {
+	opt := OptionalFields("paused", "template.spec.hostNetwork")
+	tests := []struct {
+		name          string
+		local, remote map[string]any
+		differs       bool
+	}{
+		{name: "absent locally and default remotely", local: map[string]any{}, remote: map[string]any{"paused": false}},
+		{name: "default locally and absent remotely", local: map[string]any{"paused": false}, remote: map[string]any{}},
+		{
+			name:   "nested",
+			local:  map[string]any{"template": map[string]any{"spec": map[string]any{}}},
+			remote: map[string]any{"template": map[string]any{"spec": map[string]any{"hostNetwork": false}}},
+		},
+		{name: "absent locally and set remotely", local: map[string]any{}, remote: map[string]any{"paused": true}, differs: true},
+		{name: "set locally and absent remotely", local: map[string]any{"paused": true}, remote: map[string]any{}, differs: true},
+		{name: "default and set", local: map[string]any{"paused": false}, remote: map[string]any{"paused": true}, differs: true},
+		{name: "not listed", local: map[string]any{}, remote: map[string]any{"replicas": int64(0)}, differs: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := PerspectiveManifest.DiffObj(deployment(tt.local), deployment(tt.remote), opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if differs := diff != ""; differs != tt.differs {
+				t.Errorf("differs = %v, want %v:\n%s", differs, tt.differs, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package objdiff
+
+import (
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	// FieldsAnnotation on an object in the manifests is the comma separated top-level fields compared for it
+	// like "spec,status" by Diff, which replace the ones of WithFields.
+	FieldsAnnotation = "difftool/fields"
+	// IgnoreAnnotation is the comma separated keys of the payload ignored for the object like IgnoreMapEntries,
+	// in addition to the options of the comparison.
+	IgnoreAnnotation = "difftool/ignore"
+	// SensitiveAnnotation is the comma separated keys of the payload redacted for the object by Diff
+	// like WithSensitivePaths, in addition to the ones of the option.
+	SensitiveAnnotation = "difftool/sensitive"
+)
+
+// annotatedList returns the comma separated values of the annotation of obj.
+func annotatedList(obj *Object, key string) []string {
+	var out []string
+	for _, v := range strings.Split(obj.Annotations[key], ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// fieldsOf returns the fields compared for the local object.
+func (d *Diff) fieldsOf(local *Object) []string {
+	if fields := annotatedList(local, FieldsAnnotation); len(fields) != 0 {
+		return fields
+	}
+	return d.fields
+}
+
+// sensitivePathsOf returns the paths redacted for the local object.
+func (d *Diff) sensitivePathsOf(local *Object) []string {
+	annotated := annotatedList(local, SensitiveAnnotation)
+	if len(annotated) == 0 {
+		return d.sensitivePaths
+	}
+	return append(append([]string{}, d.sensitivePaths...), annotated...)
+}
+
+// withIgnoredKeys adds the keys of IgnoreAnnotation of obj to opts.
+func withIgnoredKeys(obj *Object, opts []cmp.Option) []cmp.Option {
+	keys := annotatedList(obj, IgnoreAnnotation)
+	if len(keys) == 0 {
+		return opts
+	}
+	out := make([]cmp.Option, 0, len(opts)+1)
+	return append(append(out, opts...), IgnoreMapEntries(keys))
+}
@@ -0,0 +1,75 @@
+package objdiff
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WithoutOwnerUIDs compares only the kinds, the names and the controller flags of the ownerReferences,
+// since the UIDs of the owners in the manifests can't be known before they're created.
+func WithoutOwnerUIDs() Option {
+	return func(d *Diff) {
+		d.ignoreOwnerUIDs = true
+	}
+}
+
+// ownerIdentity is an owner reference compared by WithoutOwnerUIDs.
+type ownerIdentity struct {
+	Kind       string
+	Name       string
+	Controller bool
+}
+
+// diffOwners compares the ownerReferences regardless of the order only when the local object declares them,
+// like the finalizers. A different controller takes the first line since adoption by the wrong controller
+// or an orphaned object is usually a serious problem.
+func (d *Diff) diffOwners(local, remote *Object) string {
+	if local.OwnerReferences == nil {
+		return ""
+	}
+	x, y := d.perspective.order(local, remote)
+	var diff string
+	if d.ignoreOwnerUIDs {
+		diff = cmp.Diff(ownerIdentities(x.OwnerReferences), ownerIdentities(y.OwnerReferences))
+	} else {
+		diff = cmp.Diff(sortedOwners(x.OwnerReferences), sortedOwners(y.OwnerReferences))
+	}
+	if diff == "" {
+		return ""
+	}
+	header := "ownerReferences:\n"
+	if want, got := controllerOf(local), controllerOf(remote); want != got {
+		header += fmt.Sprintf("! controlled by %s instead of %s\n", got, want)
+	}
+	return header + diff
+}
+
+func sortedOwners(refs []v1.OwnerReference) []v1.OwnerReference {
+	out := append([]v1.OwnerReference{}, refs...)
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func ownerIdentities(refs []v1.OwnerReference) []ownerIdentity {
+	out := make([]ownerIdentity, 0, len(refs))
+	for _, r := range sortedOwners(refs) {
+		out = append(out, ownerIdentity{Kind: r.Kind, Name: r.Name, Controller: r.Controller != nil && *r.Controller})
+	}
+	return out
+}
+
+// controllerOf returns the controller of the object like "ReplicaSet web-6d4b", or "nothing" if it has none.
+func controllerOf(obj *Object) string {
+	if c := v1.GetControllerOfNoCopy(obj); c != nil {
+		return c.Kind + " " + c.Name
+	}
+	return "nothing"
+}
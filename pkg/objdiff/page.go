@@ -0,0 +1,113 @@
+package objdiff
+
+import (
+	"github.com/cockroachdb/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WithPageSize lists the remote objects in pages of at most the size, so that a huge list isn't built
+// by the server in one response. Lists are compared page by page while the next page is requested,
+// which bounds the memory to the pages in flight and brings the first results earlier.
+func WithPageSize(size int64) Option {
+	return func(d *Diff) {
+		d.pageSize = size
+	}
+}
+
+// WithSerial lists the remote objects and compares them in the calling goroutine, page after page,
+// so that nothing runs concurrently for debugging.
+func WithSerial() Option {
+	return func(d *Diff) {
+		d.serial = true
+	}
+}
+
+// listPages lists the objects page by page with list, which returns the objects of a page and the continue token,
+// and passes each page to emit before the next one is requested.
+// The pages expire if the list is compacted in the meantime, and then everything is listed again at once
+// and only the objects not emitted yet are passed, so that no object is missed or passed twice across the pages.
+func (d *Diff) listPages(opts v1.ListOptions, list func(v1.ListOptions) ([]*Object, string, error), emit func([]*Object) error) error {
+	opts.Limit = d.pageSize
+	var emitted map[string]bool
+	for {
+		objs, next, err := list(opts)
+		if kerrors.IsResourceExpired(err) && opts.Continue != "" {
+			opts.Limit, opts.Continue = 0, ""
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if next != "" && emitted == nil {
+			// the objects are remembered only when there are pages which may expire
+			emitted = make(map[string]bool)
+		}
+		if emitted != nil {
+			objs = markEmitted(objs, emitted)
+		}
+		if err := emit(objs); err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		opts.Continue = next
+	}
+}
+
+// markEmitted returns the objects not emitted yet, remembering them as emitted.
+func markEmitted(objs []*Object, emitted map[string]bool) []*Object {
+	kept := make([]*Object, 0, len(objs))
+	for _, o := range objs {
+		if !emitted[o.String()] {
+			emitted[o.String()] = true
+			kept = append(kept, o)
+		}
+	}
+	return kept
+}
+
+// errStopped is returned to the lister of streamPages once the pages aren't handled anymore.
+var errStopped = errors.New("listing stopped")
+
+// streamPages runs list in another goroutine and passes the pages it emits to handle as they come,
+// so that the next page is requested while the previous one is compared.
+// The listing is stopped once handle fails, and the error of handle is returned rather than the one of list.
+func streamPages(list func(emit func([]*Object) error) error, handle func([]*Object) error) error {
+	pages := make(chan []*Object, 1)
+	done := make(chan struct{})
+	listed := make(chan error, 1)
+	go func() {
+		defer close(pages)
+		listed <- list(func(page []*Object) error {
+			select {
+			case pages <- page:
+				return nil
+			case <-done:
+				return errStopped
+			}
+		})
+	}()
+	var err error
+	for page := range pages {
+		if err != nil {
+			continue
+		}
+		if err = handle(page); err != nil {
+			close(done)
+		}
+	}
+	if listErr := <-listed; err == nil {
+		return listErr
+	}
+	return err
+}
+
+// comparePages is streamPages, or passes the pages to handle as they're listed in the calling goroutine with WithSerial.
+func (d *Diff) comparePages(list func(emit func([]*Object) error) error, handle func([]*Object) error) error {
+	if d.serial {
+		return list(handle)
+	}
+	return streamPages(list, handle)
+}
@@ -0,0 +1,180 @@
+package objdiff
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func configMap(name string, data map[string]any) *Object {
+	return &Object{
+		TypeMeta:   v1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: name},
+		Data:       data,
+	}
+}
+
+// pagedList serves the objects in pages of the limit, failing with expired on the continue token of the page.
+func pagedList(objs []*Object, expireAt int) func(v1.ListOptions) ([]*Object, string, error) {
+	return func(opts v1.ListOptions) ([]*Object, string, error) {
+		if opts.Limit == 0 {
+			return objs, "", nil
+		}
+		start := 0
+		if opts.Continue != "" {
+			start, _ = strconv.Atoi(opts.Continue)
+			if start == expireAt {
+				return nil, "", kerrors.NewResourceExpired("the continue token is too old")
+			}
+		}
+		end := start + int(opts.Limit)
+		if end >= len(objs) {
+			return objs[start:], "", nil
+		}
+		return objs[start:end], strconv.Itoa(end), nil
+	}
+}
+
+func TestListPages(t *testing.T) {
+	var objs []*Object
+	for i := 0; i < 5; i++ {
+		objs = append(objs, configMap(fmt.Sprintf("cm-%d", i), nil))
+	}
+	tests := []struct {
+		name     string
+		expireAt int
+		pages    []int
+	}{
+		{name: "pages", expireAt: -1, pages: []int{2, 2, 1}},
+		{name: "expired", expireAt: 4, pages: []int{2, 2, 1}},
+		{name: "expired at first", expireAt: 2, pages: []int{2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Diff{pageSize: 2}
+			seen := make(map[string]int)
+			var pages []int
+			err := d.listPages(v1.ListOptions{}, pagedList(objs, tt.expireAt), func(page []*Object) error {
+				pages = append(pages, len(page))
+				for _, o := range page {
+					seen[o.Name]++
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(pages) != fmt.Sprint(tt.pages) {
+				t.Errorf("pages = %v, want %v", pages, tt.pages)
+			}
+			for _, o := range objs {
+				if seen[o.Name] != 1 {
+					t.Errorf("%s is emitted %d times", o.Name, seen[o.Name])
+				}
+			}
+		})
+	}
+}
+
+func TestStreamPages(t *testing.T) {
+	var handled []string
+	err := streamPages(func(emit func([]*Object) error) error {
+		for i := 0; i < 3; i++ {
+			if err := emit([]*Object{configMap(fmt.Sprintf("cm-%d", i), nil)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(page []*Object) error {
+		handled = append(handled, page[0].Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(handled) != "[cm-0 cm-1 cm-2]" {
+		t.Errorf("handled = %v", handled)
+	}
+}
+
+func TestStreamPagesStops(t *testing.T) {
+	failed := errors.New("failed")
+	var listErr error
+	err := streamPages(func(emit func([]*Object) error) error {
+		for i := 0; i < 100; i++ {
+			if listErr = emit([]*Object{configMap(fmt.Sprintf("cm-%d", i), nil)}); listErr != nil {
+				return listErr
+			}
+		}
+		return nil
+	}, func(page []*Object) error {
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("err = %v, want %v", err, failed)
+	}
+	if !errors.Is(listErr, errStopped) {
+		t.Errorf("the listing isn't stopped: %v", listErr)
+	}
+}
+
+func TestListMatcherAcrossPages(t *testing.T) {
+	local := []*Object{
+		configMap("a", map[string]any{"k": "1"}),
+		configMap("b", map[string]any{"k": "1"}),
+		configMap("c", map[string]any{"k": "1"}),
+	}
+	m := PerspectiveManifest.newListMatcher(local, PerspectiveManifest.samePayload, func(local, remote *Object) (string, error) {
+		return PerspectiveManifest.DiffObj(local, remote)
+	})
+	var results []DiffResult
+	for _, page := range [][]*Object{
+		{configMap("b", map[string]any{"k": "2"}), configMap("x", nil)},
+		{configMap("a", map[string]any{"k": "1"})},
+	} {
+		matched, err := m.match(page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, matched...)
+	}
+	results = append(results, m.missing()...)
+
+	got := make(map[string]Class)
+	for _, r := range results {
+		got[r.Object.Name] = r.Class
+	}
+	want := map[string]Class{"b": ClassChanged, "x": ClassExtra, "c": ClassMissing}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+func TestComparePagesSerial(t *testing.T) {
+	d := &Diff{serial: true}
+	var events []string
+	err := d.comparePages(func(emit func([]*Object) error) error {
+		for i := 0; i < 3; i++ {
+			events = append(events, fmt.Sprintf("list %d", i))
+			if err := emit([]*Object{configMap(fmt.Sprintf("cm-%d", i), nil)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(page []*Object) error {
+		events = append(events, "handle "+page[0].Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each page is handled before the next one is listed
+	want := "[list 0 handle cm-0 list 1 handle cm-1 list 2 handle cm-2]"
+	if fmt.Sprint(events) != want {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
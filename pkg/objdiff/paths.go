@@ -0,0 +1,69 @@
+package objdiff
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+)
+
+// PathDiff is a difference at a path of the payloads.
+type PathDiff struct {
+	// Path is the keys joined with dots like "containers.0.image".
+	Path string
+	// Old is the value shown as '-', which is nil if the path doesn't exist on that side.
+	Old any
+	// New is the value shown as '+', which is nil if the path doesn't exist on that side.
+	New any
+}
+
+// DiffObjPaths returns the differences of obj1 as the manifest and obj2 as the cluster by path.
+func DiffObjPaths(obj1, obj2 *Object, opts ...cmp.Option) ([]PathDiff, error) {
+	return PerspectiveManifest.DiffObjPaths(obj1, obj2, opts...)
+}
+
+// DiffObjPaths returns the differences by path, where the same options as DiffObj are applied.
+func (p Perspective) DiffObjPaths(local, remote *Object, opts ...cmp.Option) ([]PathDiff, error) {
+	x, y := p.order(local, remote)
+	xp, yp, err := extract(x, y)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	r := &pathReporter{}
+	cmp.Equal(xp, yp, append(withIgnoredKeys(local, withComparers(local, opts)), cmp.Reporter(r))...)
+	return r.diffs, nil
+}
+
+// pathReporter records the differing leaves.
+type pathReporter struct {
+	path  cmp.Path
+	diffs []PathDiff
+	// keys are the keys of the paths of diffs
+	keys [][]string
+}
+
+func (r *pathReporter) PushStep(ps cmp.PathStep) {
+	r.path = append(r.path, ps)
+}
+
+func (r *pathReporter) Report(rs cmp.Result) {
+	if rs.Equal() {
+		return
+	}
+	vx, vy := r.path.Last().Values()
+	keys := pathKeys(r.path)
+	r.diffs = append(r.diffs, PathDiff{Path: strings.Join(keys, "."), Old: valueOf(vx), New: valueOf(vy)})
+	r.keys = append(r.keys, keys)
+}
+
+func (r *pathReporter) PopStep() {
+	r.path = r.path[:len(r.path)-1]
+}
+
+func valueOf(v reflect.Value) any {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
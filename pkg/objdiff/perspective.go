@@ -0,0 +1,55 @@
+package objdiff
+
+import (
+	"fmt"
+)
+
+// Perspective decides which side of a diff is regarded as the baseline.
+type Perspective string
+
+const (
+	// PerspectiveManifest regards the manifests as the baseline.
+	// '-' is the manifest and '+' is the cluster, so a diff means the cluster drifted.
+	PerspectiveManifest Perspective = "manifest"
+	// PerspectiveCluster regards the cluster as the baseline.
+	// '-' is the cluster and '+' is the manifest, so a diff means the manifest is ahead.
+	PerspectiveCluster Perspective = "cluster"
+)
+
+func ParsePerspective(s string) (Perspective, error) {
+	switch p := Perspective(s); p {
+	case PerspectiveManifest, PerspectiveCluster:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown perspective %q", s)
+}
+
+// WithPerspective sets which side is shown as '-' and '+'. PerspectiveManifest by default.
+func WithPerspective(p Perspective) Option {
+	return func(d *Diff) {
+		d.perspective = p
+	}
+}
+
+// Labels orders the names of the local and remote sides as they are shown by '-' and '+'.
+func (p Perspective) Labels(local, remote string) (minus, plus string) {
+	if p == PerspectiveCluster {
+		return remote, local
+	}
+	return local, remote
+}
+
+// signs returns the markers for objects which only exist locally and remotely.
+func (p Perspective) signs() (local, remote string) {
+	if p == PerspectiveCluster {
+		return "+", "-"
+	}
+	return "-", "+"
+}
+
+func (p Perspective) order(local, remote *Object) (x, y *Object) {
+	if p == PerspectiveCluster {
+		return remote, local
+	}
+	return local, remote
+}
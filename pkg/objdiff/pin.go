@@ -0,0 +1,86 @@
+package objdiff
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// WithPinnedVersions makes the remote objects be fetched at the given resourceVersions
+// keyed by the identities of the objects like ObservedVersions returns, so that a diff can be reproduced later.
+// Objects fetched from subresources aren't pinned since they can't be listed.
+func WithPinnedVersions(versions map[string]string) Option {
+	return func(d *Diff) {
+		d.pinned = versions
+	}
+}
+
+// ObservedVersions returns the resourceVersions of the remote objects compared so far keyed by their identities.
+func (d *Diff) ObservedVersions() map[string]string {
+	d.observedMu.Lock()
+	defer d.observedMu.Unlock()
+	out := make(map[string]string, len(d.observed))
+	for k, v := range d.observed {
+		out[k] = v
+	}
+	return out
+}
+
+func (d *Diff) observe(objs ...*Object) {
+	d.observedMu.Lock()
+	defer d.observedMu.Unlock()
+	for _, o := range objs {
+		d.observed[o.String()] = o.ResourceVersion
+	}
+}
+
+// pin replaces the objects with the pinned versions if they differ.
+func (d *Diff) pin(resource schema.GroupVersionResource, objs []*Object) ([]*Object, error) {
+	if len(d.pinned) == 0 {
+		return objs, nil
+	}
+	for i, o := range objs {
+		rv, ok := d.pinned[o.String()]
+		if !ok || rv == o.ResourceVersion {
+			continue
+		}
+		pinned, err := d.getPinnedObj(resource, o.Namespace, o.Name, rv)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		objs[i] = pinned
+	}
+	return objs, nil
+}
+
+// getPinnedObj gets the object as it was at the resourceVersion.
+// Get can't do it since it returns any version not older than the given one, so it lists the object exactly at the version.
+func (d *Diff) getPinnedObj(resource schema.GroupVersionResource, namespace, name, rv string) (*Object, error) {
+	resp, err := d.client.
+		Resource(resource).
+		Namespace(namespace).
+		List(context.Background(), v1.ListOptions{
+			FieldSelector:        fields.OneTermEqualSelector("metadata.name", name).String(),
+			ResourceVersion:      rv,
+			ResourceVersionMatch: v1.ResourceVersionMatchExact,
+		})
+	if kerrors.IsResourceExpired(err) || kerrors.IsGone(err) {
+		return nil, errors.Wrapf(err, "the pinned resourceVersion %s of %s/%s is no longer available", rv, namespace, name)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(resp.Items) == 0 {
+		return nil, errors.WithStack(kerrors.NewNotFound(resource.GroupResource(), name))
+	}
+	newObj := new(Object)
+	err = unmarshallUnstructured(&resp.Items[0], newObj)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return newObj, nil
+}
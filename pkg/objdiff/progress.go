@@ -0,0 +1,12 @@
+package objdiff
+
+// Progress is the number of the objects fetched from the cluster and the local objects compared so far.
+type Progress struct {
+	Fetched int64
+	Diffed  int64
+}
+
+// Progress returns the progress of the diffs so far, which can be called while they're running.
+func (d *Diff) Progress() Progress {
+	return Progress{Fetched: d.fetchedCount.Load(), Diffed: d.diffedCount.Load()}
+}
@@ -0,0 +1,135 @@
+package objdiff
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+// WithProtobuf makes the objects of the built-in kinds be fetched as protobuf, which is much cheaper to decode than json
+// for large lists. Kinds the client doesn't know like custom resources are still fetched as json,
+// and so are subresources and the pinned versions.
+func WithProtobuf() Option {
+	return func(d *Diff) {
+		d.protobuf = true
+	}
+}
+
+// protobufClient returns the client fetching the resource as protobuf and the kind of it,
+// or false if the resource isn't of a built-in kind.
+func (d *Diff) protobufClient(resource schema.GroupVersionResource) (rest.Interface, schema.GroupVersionKind, bool, error) {
+	if !d.protobuf {
+		return nil, schema.GroupVersionKind{}, false, nil
+	}
+	gvk, err := d.mapper.KindFor(resource)
+	if err != nil {
+		return nil, schema.GroupVersionKind{}, false, errors.WithStack(err)
+	}
+	if !scheme.Scheme.Recognizes(gvk) {
+		return nil, schema.GroupVersionKind{}, false, nil
+	}
+
+	d.protobufMu.Lock()
+	defer d.protobufMu.Unlock()
+	gv := resource.GroupVersion()
+	if client, ok := d.protobufClients[gv]; ok {
+		return client, gvk, true, nil
+	}
+	config := rest.CopyConfig(d.config)
+	config.GroupVersion = &gv
+	config.APIPath = "/apis"
+	if gv.Group == "" {
+		config.APIPath = "/api"
+	}
+	config.ContentType = runtime.ContentTypeProtobuf
+	config.AcceptContentTypes = runtime.ContentTypeProtobuf + "," + runtime.ContentTypeJSON
+	config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	client, err := rest.RESTClientFor(config)
+	if err != nil {
+		return nil, schema.GroupVersionKind{}, false, errors.WithStack(err)
+	}
+	d.protobufClients[gv] = client
+	return client, gvk, true, nil
+}
+
+// getProtobufObj gets the remote object as protobuf, or returns false if the resource can't be.
+func (d *Diff) getProtobufObj(resource schema.GroupVersionResource, namespace, name string) (*Object, bool, error) {
+	client, gvk, ok, err := d.protobufClient(resource)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	typed, err := client.Get().
+		Namespace(namespace).
+		Resource(resource.Resource).
+		Name(name).
+		Do(context.Background()).
+		Get()
+	if err != nil {
+		return nil, true, d.labelForbidden(err)
+	}
+	obj, err := fromTyped(typed, gvk)
+	if err != nil {
+		return nil, true, errors.WithStack(err)
+	}
+	return obj, true, nil
+}
+
+// streamProtobufObjs lists the remote objects as protobuf page by page, or returns false if the resource can't be.
+func (d *Diff) streamProtobufObjs(resource schema.GroupVersionResource, namespace string, opts v1.ListOptions, emit func([]*Object) error) (bool, error) {
+	client, gvk, ok, err := d.protobufClient(resource)
+	if err != nil || !ok {
+		return false, err
+	}
+	err = d.listPages(opts, func(opts v1.ListOptions) ([]*Object, string, error) {
+		list, err := client.Get().
+			Namespace(namespace).
+			Resource(resource.Resource).
+			VersionedParams(&opts, scheme.ParameterCodec).
+			Do(context.Background()).
+			Get()
+		if err != nil {
+			return nil, "", err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		page := make([]*Object, 0, len(items))
+		for _, i := range items {
+			obj, err := fromTyped(i, gvk)
+			if err != nil {
+				return nil, "", errors.WithStack(err)
+			}
+			page = append(page, obj)
+		}
+		listMeta, err := meta.ListAccessor(list)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+		return page, listMeta.GetContinue(), nil
+	}, emit)
+	return true, err
+}
+
+// fromTyped converts a typed object decoded by the codec into an Object.
+// The kind is set since it isn't kept by the decoding.
+func fromTyped(typed runtime.Object, gvk schema.GroupVersionKind) (*Object, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(typed)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+	obj := new(Object)
+	if err := unmarshallUnstructured(u, obj); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return obj, nil
+}
@@ -0,0 +1,203 @@
+package objdiff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/strings/slices"
+)
+
+const (
+	// Redacted is shown in place of sensitive values.
+	Redacted = "<redacted>"
+	// RedactedChanged is shown in place of sensitive values of the cluster which differ from the manifests.
+	RedactedChanged = "<redacted, changed>"
+)
+
+// WithRevealedSecrets shows the values of sensitive kinds and paths in diffs, which are redacted by default.
+func WithRevealedSecrets() Option {
+	return func(d *Diff) {
+		d.revealSecrets = true
+	}
+}
+
+// WithSensitiveKinds redacts every value of the kinds in addition to Secrets.
+func WithSensitiveKinds(gks ...schema.GroupKind) Option {
+	return func(d *Diff) {
+		for _, gk := range gks {
+			d.sensitiveKinds[gk] = true
+		}
+	}
+}
+
+// WithSensitivePaths redacts the values at the paths of the payloads of any kind like "env.0.value".
+func WithSensitivePaths(paths ...string) Option {
+	return func(d *Diff) {
+		d.sensitivePaths = append(d.sensitivePaths, paths...)
+	}
+}
+
+// diffPair compares the objects like Perspective.DiffObj, or Perspective.DiffFields with WithFields,
+// but sensitive values are redacted while telling whether they've changed, so that secrets never leak to logs.
+// The annotations of the local object override the options for it.
+func (d *Diff) diffPair(local, remote *Object, opts ...cmp.Option) (string, error) {
+	structured := d.structuredMerge && !d.needsCmp(local, opts)
+	opts = withIgnoredKeys(local, withComparers(local, d.withDefaultOptions(local, opts)))
+	x, y := d.perspective.order(local, remote)
+	fields := d.fieldsOf(local)
+	if len(fields) == 0 {
+		paths := d.sensitivePathsOf(local)
+		if structured && !d.isSensitiveAt(local, paths) && !d.isSensitiveAt(remote, paths) {
+			diff, ok, err := d.diffStructured(x, y)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			if ok {
+				return diff + d.perspective.diffFinalizers(local, remote) + d.diffOwners(local, remote), nil
+			}
+		}
+		xp, yp, err := extract(x, y)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		return d.diffPayloads(local, remote, x != local, xp, yp, opts) + d.perspective.diffFinalizers(local, remote) + d.diffOwners(local, remote), nil
+	}
+	var b strings.Builder
+	for _, f := range fields {
+		xp, yp, err := extractFields(x, y, f)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		if diff := d.diffPayloads(local, remote, x != local, xp, yp, opts); diff != "" {
+			fmt.Fprintf(&b, "# %s\n%s", f, diff)
+		}
+	}
+	return b.String() + d.perspective.diffFinalizers(local, remote) + d.diffOwners(local, remote), nil
+}
+
+// diffPayloads compares the payloads of the objects, which are swapped if the local one is shown as '+'.
+func (d *Diff) diffPayloads(local, remote *Object, swapped bool, xp, yp any, opts []cmp.Option) string {
+	paths := d.sensitivePathsOf(local)
+	if d.revealSecrets || !d.isSensitiveAt(local, paths) && !d.isSensitiveAt(remote, paths) {
+		return d.render(xp, yp, opts)
+	}
+	// the options decide what differs, and the redacted values only tell it
+	r := &pathReporter{}
+	cmp.Equal(xp, yp, append(opts, cmp.Reporter(r))...)
+	differs := func(key string) bool {
+		for _, pd := range r.diffs {
+			if pd.Path == key || strings.HasPrefix(pd.Path, key+".") {
+				return true
+			}
+		}
+		return false
+	}
+	// RedactedChanged is shown on the side of the cluster
+	never := func(string) bool { return false }
+	xDiffers, yDiffers := never, differs
+	if swapped {
+		xDiffers, yDiffers = differs, never
+	}
+	all := d.sensitiveKinds[local.GroupVersionKind().GroupKind()]
+	xr, yr := redact(xp, nil, all, paths, xDiffers), redact(yp, nil, all, paths, yDiffers)
+	return d.render(xr, yr, opts)
+}
+
+// redact copies v replacing the sensitive values.
+// Every leaf is sensitive if all is true, otherwise only the values at the sensitive paths are.
+// The values which differ are replaced with RedactedChanged so that the diff still shows the change.
+func redact(v any, path []string, all bool, paths []string, differs func(string) bool) any {
+	key := strings.Join(path, ".")
+	if len(path) != 0 && slices.Contains(paths, key) {
+		return redactedValue(key, differs)
+	}
+	switch x := v.(type) {
+	case DataMap:
+		out := make(DataMap, len(x))
+		for k, e := range x {
+			out[k] = redact(e, append(slices.Clone(path), k), all, paths, differs)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, e := range x {
+			out[k] = redact(e, append(slices.Clone(path), k), all, paths, differs)
+		}
+		return out
+	case []any:
+		out := make([]any, len(x))
+		for i, e := range x {
+			out[i] = redact(e, append(slices.Clone(path), strconv.Itoa(i)), all, paths, differs)
+		}
+		return out
+	}
+	if all && v != nil {
+		return redactedValue(key, differs)
+	}
+	return v
+}
+
+func redactedValue(key string, differs func(string) bool) string {
+	if differs(key) {
+		return RedactedChanged
+	}
+	return Redacted
+}
+
+// isSensitive tells if the object has any value to be redacted.
+func (d *Diff) isSensitive(obj *Object) bool {
+	return d.isSensitiveAt(obj, d.sensitivePathsOf(obj))
+}
+
+// isSensitiveAt tells if the object is of a sensitive kind or has any value at the paths.
+func (d *Diff) isSensitiveAt(obj *Object, paths []string) bool {
+	if d.revealSecrets {
+		return false
+	}
+	if d.sensitiveKinds[obj.GroupVersionKind().GroupKind()] {
+		return true
+	}
+	if len(paths) == 0 {
+		return false
+	}
+	payload, err := getExtractor(obj.GroupVersionKind().GroupKind())(obj)
+	if err != nil {
+		return false
+	}
+	found := false
+	walkPaths(payload, nil, func(key string) {
+		found = found || slices.Contains(paths, key)
+	})
+	return found
+}
+
+func walkPaths(v any, path []string, fn func(string)) {
+	if len(path) != 0 {
+		fn(strings.Join(path, "."))
+	}
+	switch x := v.(type) {
+	case DataMap:
+		for k, e := range x {
+			walkPaths(e, append(slices.Clone(path), k), fn)
+		}
+	case map[string]any:
+		for k, e := range x {
+			walkPaths(e, append(slices.Clone(path), k), fn)
+		}
+	case []any:
+		for i, e := range x {
+			walkPaths(e, append(slices.Clone(path), strconv.Itoa(i)), fn)
+		}
+	}
+}
+
+// markRedacted flags the results of sensitive objects.
+func (d *Diff) markRedacted(results []DiffResult) {
+	for i := range results {
+		results[i].Redacted = d.isSensitive(results[i].Object)
+	}
+}
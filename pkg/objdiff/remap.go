@@ -0,0 +1,58 @@
+package objdiff
+
+import "strings"
+
+// IdentityRemap returns the namespace and the name of an object in another cluster,
+// like the name without the suffix of the environment, so that the logically same objects are paired.
+type IdentityRemap func(namespace, name string) (string, string)
+
+// RemapNamespaces remaps the namespaces of the keys to the values, and the others are kept.
+func RemapNamespaces(namespaces map[string]string) IdentityRemap {
+	return func(namespace, name string) (string, string) {
+		if ns, ok := namespaces[namespace]; ok {
+			return ns, name
+		}
+		return namespace, name
+	}
+}
+
+// RemapSuffixes replaces the suffixes of the names of the keys with the values like "-staging" with "-prod",
+// where the longest matching suffix is replaced.
+func RemapSuffixes(suffixes map[string]string) IdentityRemap {
+	return func(namespace, name string) (string, string) {
+		longest := ""
+		for from := range suffixes {
+			if strings.HasSuffix(name, from) && len(from) > len(longest) {
+				longest = from
+			}
+		}
+		if longest == "" {
+			return namespace, name
+		}
+		return namespace, strings.TrimSuffix(name, longest) + suffixes[longest]
+	}
+}
+
+// ChainRemaps applies the remaps in order.
+func ChainRemaps(remaps ...IdentityRemap) IdentityRemap {
+	return func(namespace, name string) (string, string) {
+		for _, r := range remaps {
+			namespace, name = r(namespace, name)
+		}
+		return namespace, name
+	}
+}
+
+// Remap returns a copy of obj with the identity remapped, or the ones of its items if it's a list.
+func Remap(obj *Object, remap IdentityRemap) *Object {
+	out := *obj
+	if !obj.IsList() {
+		out.Namespace, out.Name = remap(obj.Namespace, obj.Name)
+		return &out
+	}
+	out.Items = make([]*Object, len(obj.Items))
+	for i, item := range obj.Items {
+		out.Items[i] = Remap(item, remap)
+	}
+	return &out
+}
@@ -0,0 +1,59 @@
+package objdiff
+
+// WithRemoteObjects attaches the objects in the cluster to the ClassChanged results as Remote,
+// so that the fields outside the compared payloads can be inspected without fetching them again.
+// Sensitive objects are attached with their values redacted.
+func WithRemoteObjects() Option {
+	return func(d *Diff) {
+		d.remoteObjects = true
+	}
+}
+
+// attachRemote sets the remote objects of the ClassChanged results.
+func (d *Diff) attachRemote(results []DiffResult, remote []*Object) {
+	if !d.remoteObjects {
+		return
+	}
+	byKey := make(map[string]*Object, len(remote))
+	for _, o := range remote {
+		byKey[skipKey(o)] = o
+	}
+	for i, r := range results {
+		if o, ok := byKey[skipKey(r.Object)]; ok && r.Class == ClassChanged {
+			results[i].Remote = d.redactObject(r.Object, o)
+		}
+	}
+}
+
+// redactObject returns a copy of the remote object whose sensitive values are redacted by the options of the local one,
+// or the object itself if it has none. The last applied configuration of kubectl is dropped since it has them all.
+func (d *Diff) redactObject(local, remote *Object) *Object {
+	paths := d.sensitivePathsOf(local)
+	if !d.isSensitiveAt(local, paths) && !d.isSensitiveAt(remote, paths) {
+		return remote
+	}
+	all := d.sensitiveKinds[remote.GroupVersionKind().GroupKind()]
+	never := func(string) bool { return false }
+	out := *remote
+	out.Spec = redact(remote.Spec, nil, all, paths, never)
+	out.Data = redact(remote.Data, nil, all, paths, never)
+	out.BinaryData = redact(remote.BinaryData, nil, all, paths, never)
+	out.Status = redact(remote.Status, nil, all, paths, never)
+	return WithoutLastApplied(&out)
+}
+
+// WithoutLastApplied returns a copy of the object without the last applied configuration of kubectl,
+// which has the values of the object in plain text, or the object itself if it has none.
+func WithoutLastApplied(obj *Object) *Object {
+	if _, ok := obj.Annotations[LastAppliedAnnotation]; !ok {
+		return obj
+	}
+	out := *obj
+	out.Annotations = make(map[string]string, len(obj.Annotations))
+	for k, v := range obj.Annotations {
+		if k != LastAppliedAnnotation {
+			out.Annotations[k] = v
+		}
+	}
+	return &out
+}
@@ -0,0 +1,44 @@
+package objdiff
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DiffReplicas compares the template with every remote object matching the label selector in any namespace,
+// which is useful to check that the copies of a resource across namespaces are consistent.
+// A result is returned for each diverging replica, whose Object is the replica.
+// No objects matching the selector is reported as missing.
+func (d *Diff) DiffReplicas(apiVersion, kind string, template *Object, selector string, opts ...cmp.Option) ([]DiffResult, error) {
+	mapping, err := d.getResource(apiVersion, kind)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	template, err = d.convert(template, mapping.GroupVersionKind)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	replicas, err := d.getRemoteObjs(mapping.Resource, v1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(replicas) == 0 {
+		results := []DiffResult{{Class: ClassMissing, Object: template, Perspective: d.perspective}}
+		d.markRedacted(results)
+		return results, nil
+	}
+
+	results := []DiffResult{}
+	for _, replica := range replicas {
+		diff, err := d.diffPair(template, replica, opts...)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if diff != "" {
+			results = append(results, DiffResult{Class: ClassChanged, Object: replica, Diff: diff, Perspective: d.perspective, ResourceVersion: replica.ResourceVersion})
+		}
+	}
+	d.markRedacted(results)
+	return results, nil
+}
@@ -0,0 +1,42 @@
+package objdiff
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ResolveResource resolves a resource argument as kubectl takes like "cm", "deploy",
+// "deployments.apps" or "deployments.v1.apps" into the kind.
+func (d *Diff) ResolveResource(arg string) (schema.GroupVersionKind, error) {
+	mapping, err := d.getResourceFromArg(arg)
+	if err != nil {
+		return schema.GroupVersionKind{}, errors.WithStack(err)
+	}
+	return mapping.GroupVersionKind, nil
+}
+
+// getResourceFromArg is the counterpart of getResource for a resource argument, which resolves short names
+// and singular and plural forms with discovery.
+func (d *Diff) getResourceFromArg(arg string) (*meta.RESTMapping, error) {
+	fullySpecified, gr := schema.ParseResourceArg(strings.ToLower(arg))
+	var gvk schema.GroupVersionKind
+	if fullySpecified != nil {
+		// it may be a group with dots rather than a version, so ignore the error
+		gvk, _ = d.shortcuts.KindFor(*fullySpecified)
+	}
+	if gvk.Empty() {
+		var err error
+		gvk, err = d.shortcuts.KindFor(gr.WithVersion(""))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	mapping, err := d.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return mapping, nil
+}
@@ -0,0 +1,150 @@
+package objdiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Class classifies the result of comparing an object.
+type Class string
+
+const (
+	// ClassChanged means the object exists on both sides but differs.
+	ClassChanged Class = "changed"
+	// ClassMissing means the object exists in the manifests but not in the cluster.
+	ClassMissing Class = "missing"
+	// ClassExtra means the object exists in the cluster but not in the manifests.
+	ClassExtra Class = "extra"
+	// ClassTerminating means the object in the cluster has a deletion timestamp and is waiting for its finalizers.
+	ClassTerminating Class = "terminating"
+	// ClassForbidden means the object couldn't be compared since the access to the kind is denied.
+	ClassForbidden Class = "forbidden"
+	// ClassSkipped means the object in the manifests is annotated with SkipAnnotation and isn't compared.
+	ClassSkipped Class = "skipped"
+	// ClassProgressing means the controller of the object in the cluster hasn't caught up with its generation
+	// with WithGenerationCheck.
+	ClassProgressing Class = "progressing"
+	// ClassRejected means the object is missing from the cluster and the server rejects to create it with WithDryRunCreate.
+	ClassRejected Class = "rejected"
+)
+
+// DiffResult is the result of comparing an object.
+type DiffResult struct {
+	Class Class
+	// Object is the local object if any, otherwise the remote one.
+	// It's always the remote one for ClassTerminating and ClassProgressing to tell their states.
+	Object *Object
+	// Diff is the diff of the payloads. It's empty unless the class is ClassChanged,
+	// or it tells the generations for ClassProgressing and why the creation fails for ClassRejected.
+	Diff string
+	// Perspective tells which side is shown as '-' and '+' in Diff.
+	Perspective Perspective
+	// ResourceVersion is the version of the remote object compared. It's empty if the class is ClassMissing.
+	ResourceVersion string
+	// Redacted means the values of the object are sensitive and aren't shown in Diff.
+	Redacted bool
+	// Remote is the object in the cluster of a ClassChanged result with WithRemoteObjects, whose sensitive values are redacted.
+	Remote *Object
+	// DriftScore is the sum of the weights of the differing paths of a ClassChanged result with WithDriftScores.
+	DriftScore float64
+}
+
+// SortResults sorts the results by the group and kind, the namespace and then the name of the objects,
+// so that the order doesn't depend on the order the objects are fetched in. Results of the same object keep their order.
+func SortResults(results []DiffResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		x, y := results[i].Object, results[j].Object
+		if gx, gy := x.GroupVersionKind().GroupKind().String(), y.GroupVersionKind().GroupKind().String(); gx != gy {
+			return gx < gy
+		}
+		if x.Namespace != y.Namespace {
+			return x.Namespace < y.Namespace
+		}
+		return x.Name < y.Name
+	})
+}
+
+// String returns the result in plain text with DefaultFormatter.
+func (r DiffResult) String() string {
+	return DefaultFormatter.Format(r)
+}
+
+// Formatter formats results in plain text.
+// The messages of objects existing on only one side are text/templates
+// given the result as .Result, the object as .Object and the marker of the side as .Sign.
+type Formatter struct {
+	missing *template.Template
+	extra   *template.Template
+}
+
+const (
+	DefaultMissingMessage = "{{.Sign}} {{.Object}} is missing from the cluster"
+	DefaultExtraMessage   = "{{.Sign}} {{.Object}} exists in the cluster but not in the manifests"
+)
+
+var DefaultFormatter = MustFormatter(DefaultMissingMessage, DefaultExtraMessage)
+
+func NewFormatter(missing, extra string) (*Formatter, error) {
+	m, err := template.New("missing").Parse(missing)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't parse the missing message")
+	}
+	e, err := template.New("extra").Parse(extra)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't parse the extra message")
+	}
+	return &Formatter{missing: m, extra: e}, nil
+}
+
+func MustFormatter(missing, extra string) *Formatter {
+	f, err := NewFormatter(missing, extra)
+	if err != nil {
+		panic(err.Error())
+	}
+	return f
+}
+
+type messageData struct {
+	Result DiffResult
+	Object *Object
+	Sign   string
+}
+
+func (f *Formatter) Format(r DiffResult) string {
+	localSign, remoteSign := r.Perspective.signs()
+	var t *template.Template
+	data := messageData{Result: r, Object: r.Object}
+	switch r.Class {
+	case ClassMissing:
+		t, data.Sign = f.missing, localSign
+	case ClassExtra:
+		t, data.Sign = f.extra, remoteSign
+	case ClassForbidden:
+		return fmt.Sprintf("! %s couldn't be compared since the access is denied\n", r.Object)
+	case ClassSkipped:
+		return fmt.Sprintf("~ %s is skipped by %s\n", r.Object, SkipAnnotation)
+	case ClassProgressing:
+		return fmt.Sprintf("%s %s is progressing: %s", remoteSign, r.Object, r.Diff)
+	case ClassRejected:
+		return fmt.Sprintf("! %s is missing from the cluster and would be rejected: %s", r.Object, r.Diff)
+	case ClassTerminating:
+		return fmt.Sprintf("%s %s is terminating since %s\n", remoteSign, r.Object, r.Object.DeletionTimestamp.UTC().Format(time.RFC3339))
+	default:
+		if r.DriftScore > 0 {
+			return fmt.Sprintf("%s (drift %g)\n%s", r.Object, r.DriftScore, r.Diff)
+		}
+		return fmt.Sprintf("%s\n%s", r.Object, r.Diff)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		// show the error in place rather than losing the result
+		return fmt.Sprintf("%s %s (%s): %v\n", data.Sign, r.Object, r.Class, err)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
@@ -0,0 +1,199 @@
+package objdiff
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/strings/slices"
+)
+
+var crdResource = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+// SchemaFinding is a field of a live object deviating from the schema of the CRD.
+type SchemaFinding struct {
+	// Path is the keys joined with dots from the spec like "replicas".
+	Path    string
+	Message string
+}
+
+func (f SchemaFinding) String() string {
+	return fmt.Sprintf("%s: %s", f.Path, f.Message)
+}
+
+// CheckSchema compares the spec of the live object identified by obj with the openAPIV3Schema of its CRD,
+// independently of the manifest. It reports the fields deviating from the declared defaults
+// and the ones violating the schema by their types, enums, required fields and unknown fields,
+// which helps to find objects mutated out of band. Only custom resources can be checked.
+func (d *Diff) CheckSchema(apiVersion, kind string, obj *Object) ([]SchemaFinding, error) {
+	mapping, err := d.getResource(apiVersion, kind)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	specSchema, err := d.getSpecSchema(mapping.Resource)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	live, err := d.getRemoteObj(mapping.Resource, obj)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var findings []SchemaFinding
+	checkSchema(nil, live.Spec, specSchema, &findings)
+	return findings, nil
+}
+
+// getSpecSchema returns the schema of the spec served at the version of the resource.
+func (d *Diff) getSpecSchema(resource schema.GroupVersionResource) (map[string]any, error) {
+	name := resource.Resource + "." + resource.Group
+	crd, err := d.client.Resource(crdResource).Get(context.Background(), name, v1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't get the CRD %s", name)
+	}
+	versions, _ := lookup(crd.Object, "spec", "versions").([]any)
+	for _, v := range versions {
+		version, ok := v.(map[string]any)
+		if !ok || version["name"] != resource.Version {
+			continue
+		}
+		root, _ := lookup(version, "schema", "openAPIV3Schema").(map[string]any)
+		spec, _ := lookup(root, "properties", "spec").(map[string]any)
+		if spec == nil {
+			return nil, fmt.Errorf("the CRD %s has no schema of the spec at %s", name, resource.Version)
+		}
+		return spec, nil
+	}
+	return nil, fmt.Errorf("the CRD %s doesn't serve %s", name, resource.Version)
+}
+
+func lookup(v any, keys ...string) any {
+	for _, k := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+func checkSchema(path []string, v any, s map[string]any, findings *[]SchemaFinding) {
+	if s == nil {
+		return
+	}
+	report := func(format string, args ...any) {
+		*findings = append(*findings, SchemaFinding{Path: joinPath(path), Message: fmt.Sprintf(format, args...)})
+	}
+
+	if def, ok := s["default"]; ok && v != nil && !reflect.DeepEqual(normalizeNumber(def), normalizeNumber(v)) {
+		report("%v differs from the default %v", v, def)
+	}
+	if v == nil {
+		return
+	}
+	if t, ok := s["type"].(string); ok && !matchesType(t, v) {
+		report("%v is not %s", v, t)
+		return
+	}
+	if enum, ok := s["enum"].([]any); ok && !containsValue(enum, v) {
+		report("%v is not one of %v", v, enum)
+	}
+
+	switch x := v.(type) {
+	case map[string]any:
+		props, _ := s["properties"].(map[string]any)
+		if required, ok := s["required"].([]any); ok {
+			for _, r := range required {
+				if name, ok := r.(string); ok {
+					if _, ok := x[name]; !ok {
+						report("required field %s is missing", name)
+					}
+				}
+			}
+		}
+		additional, _ := s["additionalProperties"].(map[string]any)
+		preserve, _ := s["x-kubernetes-preserve-unknown-fields"].(bool)
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := append(slices.Clone(path), k)
+			if ps, ok := props[k].(map[string]any); ok {
+				checkSchema(child, x[k], ps, findings)
+			} else if additional != nil {
+				checkSchema(child, x[k], additional, findings)
+			} else if props != nil && !preserve {
+				*findings = append(*findings, SchemaFinding{Path: joinPath(child), Message: "unknown field"})
+			}
+		}
+	case []any:
+		items, _ := s["items"].(map[string]any)
+		for i, e := range x {
+			checkSchema(append(slices.Clone(path), strconv.Itoa(i)), e, items, findings)
+		}
+	}
+}
+
+func joinPath(path []string) string {
+	if len(path) == 0 {
+		return "."
+	}
+	return strings.Join(path, ".")
+}
+
+func matchesType(t string, v any) bool {
+	switch t {
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "integer":
+		switch n := v.(type) {
+		case int64:
+			return true
+		case float64:
+			return n == float64(int64(n))
+		}
+		return false
+	case "number":
+		switch v.(type) {
+		case int64, float64:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+func containsValue(enum []any, v any) bool {
+	for _, e := range enum {
+		if reflect.DeepEqual(normalizeNumber(e), normalizeNumber(v)) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeNumber makes int64 and float64 comparable since the schema and the object may be decoded differently.
+func normalizeNumber(v any) any {
+	if n, ok := v.(int64); ok {
+		return float64(n)
+	}
+	return v
+}
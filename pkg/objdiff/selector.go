@@ -0,0 +1,41 @@
+package objdiff
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// SelectorAnnotation on an object in the manifests without a name is the label selector of the remote object
+// which it's compared with by Diff, like the one pod with a generated name.
+// It's an error unless exactly one object in the namespace of the local one matches the selector.
+const SelectorAnnotation = "difftool/selector"
+
+// selectOne returns a copy of obj named after the only remote object matching its SelectorAnnotation,
+// or obj as is if it has a name or no selector.
+func (d *Diff) selectOne(resource schema.GroupVersionResource, obj *Object) (*Object, error) {
+	selector := obj.Annotations[SelectorAnnotation]
+	if obj.Name != "" || selector == "" {
+		return obj, nil
+	}
+	matched, err := d.getRemoteObjsIn(resource, obj.Namespace, v1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't list the objects matching %q", selector)
+	}
+	switch len(matched) {
+	case 0:
+		return nil, errors.Newf("no %s matches %q", resource.Resource, selector)
+	case 1:
+	default:
+		names := make([]string, 0, len(matched))
+		for _, o := range matched {
+			names = append(names, o.Name)
+		}
+		return nil, errors.Newf("%d %s match %q: %s", len(matched), resource.Resource, selector, strings.Join(names, ", "))
+	}
+	out := *obj
+	out.Name = matched[0].Name
+	return &out, nil
+}
@@ -0,0 +1,66 @@
+package objdiff
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ElideUnchanged replaces the unchanged lines of a diff farther than `context` lines from any change
+// with a line telling how many are omitted. go-cmp shows every unchanged field of a modified struct or map,
+// so this keeps the output proportional to the change for large specs.
+// The first and the last lines are always kept to show which value the diff belongs to.
+// A negative context keeps the diff as it is.
+func ElideUnchanged(diff string, context int) string {
+	if context < 0 || diff == "" {
+		return diff
+	}
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	keep := make([]bool, len(lines))
+	keep[0], keep[len(lines)-1] = true, true
+	for i, l := range lines {
+		if !isChangedLine(l) {
+			continue
+		}
+		for j := i - context; j <= i+context; j++ {
+			if j >= 0 && j < len(lines) {
+				keep[j] = true
+			}
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(lines); i++ {
+		if keep[i] {
+			b.WriteString(lines[i])
+			b.WriteString("\n")
+			continue
+		}
+		start := i
+		for i+1 < len(lines) && !keep[i+1] {
+			i++
+		}
+		indent := lines[start][:len(lines[start])-len(strings.TrimLeft(lines[start], " \t\u00a0"))]
+		fmt.Fprintf(&b, "%s... // %d unchanged lines\n", indent, i-start+1)
+	}
+	return b.String()
+}
+
+// TruncateValues cuts the lines of a diff longer than width runes, such as embedded documents in a single string.
+// A non-positive width keeps the diff as it is.
+func TruncateValues(diff string, width int) string {
+	if width <= 0 {
+		return diff
+	}
+	lines := strings.Split(diff, "\n")
+	for i, l := range lines {
+		if utf8.RuneCountInString(l) > width {
+			lines[i] = string([]rune(l)[:width]) + "…"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isChangedLine(l string) bool {
+	return strings.HasPrefix(l, "-") || strings.HasPrefix(l, "+")
+}
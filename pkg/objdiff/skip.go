@@ -0,0 +1,46 @@
+package objdiff
+
+// SkipAnnotation skips an object in the manifests entirely when it's "true", like the objects managed out of band.
+// The object isn't fetched nor compared, and it's reported as ClassSkipped instead.
+const SkipAnnotation = "difftool/skip"
+
+func isSkipped(obj *Object) bool {
+	return obj.Annotations[SkipAnnotation] == "true"
+}
+
+// skipKey identifies an object regardless of its version since the remote one may be of another version.
+func skipKey(obj *Object) string {
+	return obj.GroupVersionKind().GroupKind().String() + "/" + obj.Namespace + "/" + obj.Name
+}
+
+// pruneSkipped removes the items annotated with SkipAnnotation, and returns ClassSkipped results for them.
+func (d *Diff) pruneSkipped(items []*Object) ([]*Object, []DiffResult) {
+	var skipped []DiffResult
+	kept := make([]*Object, 0, len(items))
+	for _, i := range items {
+		if isSkipped(i) {
+			skipped = append(skipped, DiffResult{Class: ClassSkipped, Object: i, Perspective: d.perspective})
+			continue
+		}
+		kept = append(kept, i)
+	}
+	return kept, skipped
+}
+
+// pruneSkippedRemote removes the remote objects of the skipped items so that they aren't reported as extra.
+func pruneSkippedRemote(remote []*Object, skipped []DiffResult) []*Object {
+	if len(skipped) == 0 {
+		return remote
+	}
+	keys := make(map[string]bool, len(skipped))
+	for _, r := range skipped {
+		keys[skipKey(r.Object)] = true
+	}
+	kept := make([]*Object, 0, len(remote))
+	for _, o := range remote {
+		if !keys[skipKey(o)] {
+			kept = append(kept, o)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,62 @@
+package objdiff
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type staticDiffer struct {
+	objects []*Object
+	byKey   map[string]*Object
+}
+
+// NewStatic returns a Differ comparing with the given objects as the cluster without any requests,
+// e.g. to diff two sets loaded beforehand. Objects are matched by their identities.
+func NewStatic(objects []*Object) Differ {
+	byKey := make(map[string]*Object, len(objects))
+	for _, o := range objects {
+		byKey[o.String()] = o
+	}
+	return &staticDiffer{objects: objects, byKey: byKey}
+}
+
+func (s *staticDiffer) Diff(apiVersion, kind string, obj *Object, opts ...cmp.Option) ([]DiffResult, error) {
+	if obj.IsList() {
+		// a list can contain different kinds like diffList fetches
+		kinds := map[schema.GroupKind]bool{schema.FromAPIVersionAndKind(apiVersion, kind).GroupKind(): true}
+		for _, i := range obj.Items {
+			if i.Kind != "" {
+				kinds[i.GroupVersionKind().GroupKind()] = true
+			}
+		}
+		var remote []*Object
+		for _, o := range s.objects {
+			if kinds[o.GroupVersionKind().GroupKind()] {
+				remote = append(remote, o)
+			}
+		}
+		results, err := PerspectiveManifest.DiffList(obj.Items, remote, opts...)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return results, nil
+	}
+
+	remote, ok := s.byKey[obj.String()]
+	if !ok {
+		return []DiffResult{{Class: ClassMissing, Object: obj, Perspective: PerspectiveManifest}}, nil
+	}
+	results := PerspectiveManifest.terminating(remote)
+	diff, err := PerspectiveManifest.DiffObj(obj, remote, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if diff != "" {
+		results = append(results, DiffResult{Class: ClassChanged, Object: obj, Diff: diff, Perspective: PerspectiveManifest, ResourceVersion: remote.ResourceVersion})
+	}
+	if results == nil {
+		return []DiffResult{}, nil
+	}
+	return results, nil
+}
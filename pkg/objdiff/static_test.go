@@ -0,0 +1,65 @@
+package objdiff
+
+import (
+	"fmt"
+	"testing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStaticDiffer(t *testing.T) {
+	d := NewStatic([]*Object{
+		configMap("same", map[string]any{"k": "v"}),
+		configMap("changed", map[string]any{"k": "new"}),
+		configMap("extra", map[string]any{"k": "v"}),
+		deployment(map[string]any{"replicas": int64(1)}),
+	})
+	tests := []struct {
+		name string
+		obj  *Object
+		want map[string]Class
+	}{
+		{name: "same", obj: configMap("same", map[string]any{"k": "v"}), want: map[string]Class{}},
+		{name: "changed", obj: configMap("changed", map[string]any{"k": "old"}), want: map[string]Class{"changed": ClassChanged}},
+		{name: "missing", obj: configMap("missing", map[string]any{"k": "v"}), want: map[string]Class{"missing": ClassMissing}},
+		{
+			name: "list",
+			obj: &Object{
+				TypeMeta: v1.TypeMeta{APIVersion: "v1", Kind: "ConfigMapList"},
+				Items: []*Object{
+					configMap("same", map[string]any{"k": "v"}),
+					configMap("changed", map[string]any{"k": "old"}),
+					configMap("missing", map[string]any{"k": "v"}),
+				},
+			},
+			// the deployment isn't of the kinds of the list
+			want: map[string]Class{"changed": ClassChanged, "missing": ClassMissing, "extra": ClassExtra},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := d.Diff("v1", "ConfigMap", tt.obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]Class)
+			for _, r := range results {
+				got[r.Object.Name] = r.Class
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticDifferShowsDiff(t *testing.T) {
+	d := NewStatic([]*Object{configMap("changed", map[string]any{"k": "new"})})
+	results, err := d.Diff("v1", "ConfigMap", configMap("changed", map[string]any{"k": "old"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Diff == "" {
+		t.Fatalf("results = %+v, want a diff", results)
+	}
+}
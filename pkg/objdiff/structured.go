@@ -0,0 +1,251 @@
+package objdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/managedfields"
+	"k8s.io/kube-openapi/pkg/util/proto"
+	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+	"sigs.k8s.io/structured-merge-diff/v4/typed"
+	"sigs.k8s.io/structured-merge-diff/v4/value"
+)
+
+// structuredFields are the top-level fields compared by the structured merge, which are the payloads of the builtin extractors.
+var structuredFields = []string{"spec", "data", "binaryData"}
+
+// WithStructuredMerge compares the objects of the kinds in the OpenAPI schema of the cluster like the server applies them,
+// so that associative lists like containers are matched by their merge keys rather than their indices.
+// The ignored annotations of the Diff are dropped before the comparison, and the kinds the schema doesn't know,
+// the sensitive objects, the objects compared with WithFields and the ones given the options of go-cmp
+// like ignored keys or registered comparers are compared with go-cmp as usual so that the options aren't lost.
+func WithStructuredMerge() Option {
+	return func(d *Diff) {
+		d.structuredMerge = true
+	}
+}
+
+// needsCmp tells if the options of go-cmp other than the ignored annotations apply to the local object,
+// which the structured merge can't honor.
+func (d *Diff) needsCmp(local *Object, opts []cmp.Option) bool {
+	if len(opts) != 0 || len(annotatedList(local, IgnoreAnnotation)) != 0 || len(d.generatedFieldsOf(local)) != 0 {
+		return true
+	}
+	comparersMu.RLock()
+	defer comparersMu.RUnlock()
+	return len(comparers[local.GroupVersionKind().GroupKind()]) != 0
+}
+
+// structuredParser returns the parser of the OpenAPI schema of the cluster, which is loaded once.
+func (d *Diff) structuredParser() (*managedfields.GvkParser, error) {
+	d.structuredOnce.Do(func() {
+		doc, err := d.discovery.OpenAPISchema()
+		if err != nil {
+			d.structuredErr = errors.Wrapf(err, "couldn't get the OpenAPI schema")
+			return
+		}
+		models, err := proto.NewOpenAPIData(doc)
+		if err != nil {
+			d.structuredErr = errors.Wrapf(err, "couldn't parse the OpenAPI schema")
+			return
+		}
+		parser, err := managedfields.NewGVKParser(models, false)
+		if err != nil {
+			d.structuredErr = errors.Wrapf(err, "couldn't build the types of the OpenAPI schema")
+			return
+		}
+		d.structuredTypes = parser
+	})
+	return d.structuredTypes, d.structuredErr
+}
+
+// diffStructured compares the objects by the structured merge, or returns false if they can't be.
+func (d *Diff) diffStructured(x, y *Object) (string, bool, error) {
+	parser, err := d.structuredParser()
+	if err != nil {
+		return "", false, err
+	}
+	t := parser.Type(x.GroupVersionKind())
+	if t == nil {
+		return "", false, nil
+	}
+	xu, err := structuredContent(x)
+	if err != nil {
+		return "", false, errors.WithStack(err)
+	}
+	yu, err := structuredContent(y)
+	if err != nil {
+		return "", false, errors.WithStack(err)
+	}
+	d.dropIgnoredAnnotations(xu)
+	d.dropIgnoredAnnotations(yu)
+	// objects which don't conform to the schema like the ones with unknown fields are left to go-cmp
+	xt, err := t.FromUnstructured(xu)
+	if err != nil {
+		return "", false, nil
+	}
+	yt, err := t.FromUnstructured(yu)
+	if err != nil {
+		return "", false, nil
+	}
+	c, err := xt.Compare(yt)
+	if err != nil {
+		return "", false, nil
+	}
+	return renderComparison(c, xu, yu), true, nil
+}
+
+// structuredContent returns the compared fields of the object as unstructured content.
+func structuredContent(obj *Object) (map[string]any, error) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var all map[string]any
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	out := map[string]any{"apiVersion": all["apiVersion"], "kind": all["kind"]}
+	for _, f := range structuredFields {
+		if v, ok := all[f]; ok {
+			out[f] = v
+		}
+	}
+	return out, nil
+}
+
+// dropIgnoredAnnotations drops the annotations ignored by the Diff wherever they are in the content
+// like in the pod template, as IgnoreAnnotations and IgnoreAnnotationPrefixes ignore them in go-cmp.
+func (d *Diff) dropIgnoredAnnotations(v any) {
+	switch x := v.(type) {
+	case []any:
+		for _, e := range x {
+			d.dropIgnoredAnnotations(e)
+		}
+	case map[string]any:
+		for k, e := range x {
+			if annotations, ok := e.(map[string]any); ok && k == "annotations" {
+				for key := range annotations {
+					if d.isIgnoredAnnotation(key) {
+						delete(annotations, key)
+					}
+				}
+				// an empty map would differ from the absent one
+				if len(annotations) == 0 {
+					delete(x, k)
+				}
+				continue
+			}
+			d.dropIgnoredAnnotations(e)
+		}
+	}
+}
+
+func (d *Diff) isIgnoredAnnotation(key string) bool {
+	if slices.Contains(d.ignoredAnnotations, key) {
+		return true
+	}
+	for _, p := range d.ignoredAnnotationPrefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+type structuredChange struct {
+	path string
+	line string
+}
+
+// renderComparison shows the removed, modified and added paths sorted by the paths.
+// Only the topmost path of a removed or added subtree is shown with the whole value.
+func renderComparison(c *typed.Comparison, x, y map[string]any) string {
+	var changes []structuredChange
+	add := func(set *fieldpath.Set, fn func(p fieldpath.Path) string) {
+		var shown []string
+		set.Iterate(func(p fieldpath.Path) {
+			key := p.String()
+			for _, s := range shown {
+				if strings.HasPrefix(key, s+".") || strings.HasPrefix(key, s+"[") {
+					return
+				}
+			}
+			shown = append(shown, key)
+			changes = append(changes, structuredChange{path: key, line: fn(p)})
+		})
+	}
+	add(c.Removed, func(p fieldpath.Path) string {
+		return fmt.Sprintf("- %s: %s\n", p, valueString(x, p))
+	})
+	add(c.Modified, func(p fieldpath.Path) string {
+		return fmt.Sprintf("- %s: %s\n+ %s: %s\n", p, valueString(x, p), p, valueString(y, p))
+	})
+	add(c.Added, func(p fieldpath.Path) string {
+		return fmt.Sprintf("+ %s: %s\n", p, valueString(y, p))
+	})
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].path < changes[j].path
+	})
+	var b strings.Builder
+	for _, ch := range changes {
+		b.WriteString(ch.line)
+	}
+	return b.String()
+}
+
+// valueString returns the value at the path in json.
+func valueString(v any, p fieldpath.Path) string {
+	b, err := json.Marshal(valueAt(v, p))
+	if err != nil {
+		return fmt.Sprint(valueAt(v, p))
+	}
+	return string(b)
+}
+
+// valueAt walks the unstructured content along the path.
+func valueAt(v any, p fieldpath.Path) any {
+	for _, pe := range p {
+		switch {
+		case pe.FieldName != nil:
+			m, _ := v.(map[string]any)
+			v = m[*pe.FieldName]
+		case pe.Index != nil:
+			s, _ := v.([]any)
+			if *pe.Index >= len(s) {
+				return nil
+			}
+			v = s[*pe.Index]
+		case pe.Key != nil:
+			v = findElement(v, func(e any) bool {
+				m, _ := e.(map[string]any)
+				for _, f := range *pe.Key {
+					if !value.Equals(value.NewValueInterface(m[f.Name]), f.Value) {
+						return false
+					}
+				}
+				return true
+			})
+		case pe.Value != nil:
+			v = findElement(v, func(e any) bool {
+				return value.Equals(value.NewValueInterface(e), *pe.Value)
+			})
+		}
+	}
+	return v
+}
+
+func findElement(v any, fn func(any) bool) any {
+	s, _ := v.([]any)
+	for _, e := range s {
+		if fn(e) {
+			return e
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package objdiff
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNeedsCmp(t *testing.T) {
+	d := &Diff{}
+	plain := deployment(map[string]any{"replicas": int64(1)})
+	annotated := deployment(map[string]any{"replicas": int64(1)})
+	annotated.Annotations = map[string]string{IgnoreAnnotation: "spec.replicas"}
+	tests := []struct {
+		name string
+		obj  *Object
+		opts []cmp.Option
+		want bool
+	}{
+		{name: "no options", obj: plain},
+		{name: "target options", obj: plain, opts: []cmp.Option{IgnoreMapEntries([]string{"spec.replicas"})}, want: true},
+		{name: "ignore annotation", obj: annotated, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.needsCmp(tt.obj, tt.opts); got != tt.want {
+				t.Errorf("needsCmp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDropIgnoredAnnotations(t *testing.T) {
+	d := &Diff{ignoredAnnotations: DefaultIgnoredAnnotations, ignoredAnnotationPrefixes: DefaultIgnoredAnnotationPrefixes}
+	content := map[string]any{"spec": map[string]any{"template": map[string]any{
+		"metadata": map[string]any{"annotations": map[string]any{"kubectl.kubernetes.io/restartedAt": "now"}},
+		"spec": map[string]any{"containers": []any{map[string]any{"name": "app", "annotations": map[string]any{
+			"meta.helm.sh/release-name": "app", "team": "a",
+		}}}},
+	}}}
+	d.dropIgnoredAnnotations(content)
+	want := map[string]any{"spec": map[string]any{"template": map[string]any{
+		"metadata": map[string]any{},
+		"spec":     map[string]any{"containers": []any{map[string]any{"name": "app", "annotations": map[string]any{"team": "a"}}}},
+	}}}
+	if fmt.Sprint(content) != fmt.Sprint(want) {
+		t.Errorf("content = %v, want %v", content, want)
+	}
+}
@@ -0,0 +1,43 @@
+package objdiff
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// WithSubresource makes a single object of the kind be fetched from the subresource like "scale"
+// instead of the object itself. Since a subresource only carries a part of the object,
+// only the fields of the spec present in the subresource are compared.
+// It doesn't affect lists since subresources can't be listed.
+func WithSubresource(gk schema.GroupKind, subresource string) Option {
+	return func(d *Diff) {
+		d.subresources[gk] = subresource
+	}
+}
+
+func (d *Diff) subresourcesOf(obj *Object) []string {
+	if s, ok := d.subresources[obj.GroupVersionKind().GroupKind()]; ok {
+		return []string{s}
+	}
+	return nil
+}
+
+// projectSpec returns a copy of obj whose spec only has the top-level fields in the spec of sub.
+func projectSpec(obj, sub *Object) *Object {
+	spec, ok := obj.Spec.(map[string]any)
+	if !ok {
+		return obj
+	}
+	subSpec, ok := sub.Spec.(map[string]any)
+	if !ok {
+		return obj
+	}
+	projected := make(map[string]any, len(subSpec))
+	for k := range subSpec {
+		if v, ok := spec[k]; ok {
+			projected[k] = v
+		}
+	}
+	out := *obj
+	out.Spec = projected
+	return &out
+}
@@ -0,0 +1,98 @@
+package objdiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// WithDataSummary shows the diffs of ConfigMap and Secret data as the changed, added and removed keys
+// under a header counting them, which is easier to scan than the structural diff for maps with many keys.
+func WithDataSummary() Option {
+	return func(d *Diff) {
+		d.dataSummary = true
+	}
+}
+
+// render returns the diff of the payloads, summarizing data with WithDataSummary and collapsing subtrees with WithMaxDepth.
+func (d *Diff) render(x, y any, opts []cmp.Option) string {
+	if d.dataSummary {
+		xm, xok := asDataMap(x)
+		ym, yok := asDataMap(y)
+		if xok && yok {
+			return summarizeData(xm, ym, opts)
+		}
+	}
+	if d.maxDepth > 0 {
+		return diffDepth(x, y, d.maxDepth, opts)
+	}
+	return cmp.Diff(x, y, opts...)
+}
+
+// asDataMap returns the payload as a DataMap, where no data is an empty one.
+func asDataMap(v any) (DataMap, bool) {
+	if v == nil {
+		return DataMap{}, true
+	}
+	m, ok := v.(DataMap)
+	return m, ok
+}
+
+// summarizeData lists the keys which differ by the options sorted by the keys.
+// Each key is compared alone in a DataMap so that the options scoped to data keys still apply.
+func summarizeData(x, y DataMap, opts []cmp.Option) string {
+	keys := make([]string, 0, len(x)+len(y))
+	for k := range x {
+		keys = append(keys, k)
+	}
+	for k := range y {
+		if _, ok := x[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changed, added, removed int
+	var b strings.Builder
+	for _, k := range keys {
+		xv, xok := x[k]
+		yv, yok := y[k]
+		xk, yk := DataMap{}, DataMap{}
+		if xok {
+			xk[k] = xv
+		}
+		if yok {
+			yk[k] = yv
+		}
+		if cmp.Equal(xk, yk, opts...) {
+			continue
+		}
+		switch {
+		case !yok:
+			removed++
+			fmt.Fprintf(&b, "- %s: %s\n", k, dataValueString(xv))
+		case !xok:
+			added++
+			fmt.Fprintf(&b, "+ %s: %s\n", k, dataValueString(yv))
+		default:
+			changed++
+			fmt.Fprintf(&b, "~ %s:\n%s", k, cmp.Diff(xk, yk, opts...))
+		}
+	}
+	if changed+added+removed == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d keys changed, %d added, %d removed\n", changed, added, removed) + b.String()
+}
+
+func dataValueString(v any) string {
+	switch x := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", x)
+	case BinaryValue:
+		return fmt.Sprintf("%q (binary)", string(x))
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,99 @@
+package objdiff
+
+import (
+	"reflect"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/json"
+)
+
+// LastAppliedAnnotation is the annotation where kubectl apply records the applied configuration.
+const LastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// LastApplied returns the object recorded in the last-applied annotation of live, or nil if there's none.
+func LastApplied(live *Object) (*Object, error) {
+	v, ok := live.Annotations[LastAppliedAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	base := new(Object)
+	if err := json.Unmarshal([]byte(v), base); err != nil {
+		return nil, errors.Wrapf(err, "couldn't parse the last applied configuration of %s", live)
+	}
+	return base, nil
+}
+
+// ThreeWayDiff predicts the payload after applying local as kubectl client-side apply does,
+// and returns it with the diff from live, where '-' is live and '+' is the merged one.
+// base is the last applied object, which is taken from the annotation of live if it's nil.
+//
+// Fields set in local overwrite live, and fields in base but not in local are deleted,
+// so fields set by others than the applier are kept.
+// It's a prediction by a json merge, so it differs from an actual apply in the ways below.
+//   - lists are replaced as a whole, whereas strategic merge patch merges some of them by keys.
+//   - server side defaulting, admission webhooks and validation are not reflected.
+//
+// Server-side apply with dry-run is more faithful where it's available.
+func ThreeWayDiff(local, live, base *Object, opts ...cmp.Option) (any, string, error) {
+	if base == nil {
+		var err error
+		base, err = LastApplied(live)
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+	}
+	if base == nil {
+		base = &Object{TypeMeta: local.TypeMeta}
+	}
+
+	fn := getExtractor(local.GroupVersionKind().GroupKind())
+	payloads := make([]any, 0, 3)
+	for _, o := range []*Object{local, live, base} {
+		p, err := fn(o)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "couldn't extract %s", o)
+		}
+		payloads = append(payloads, p)
+	}
+	merged := threeWayMerge(payloads[0], payloads[1], payloads[2])
+	return merged, cmp.Diff(payloads[1], merged, withIgnoredKeys(local, withComparers(local, opts))...), nil
+}
+
+// threeWayMerge merges local into live, deleting the keys removed from base.
+func threeWayMerge(local, live, base any) any {
+	lm, lok := asMap(local)
+	vm, vok := asMap(live)
+	if !lok || !vok {
+		return local
+	}
+	bm, _ := asMap(base)
+
+	merged := make(map[string]any, len(vm))
+	for k, v := range vm {
+		merged[k] = v
+	}
+	for k := range bm {
+		if _, ok := lm[k]; !ok {
+			delete(merged, k)
+		}
+	}
+	for k, v := range lm {
+		merged[k] = threeWayMerge(v, vm[k], bm[k])
+	}
+	return convertMap(live, merged)
+}
+
+// asMap returns v as a map if it's a map of strings, including named ones like DataMap.
+func asMap(v any) (map[string]any, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || !rv.Type().ConvertibleTo(reflect.TypeOf(map[string]any(nil))) {
+		return nil, false
+	}
+	return rv.Convert(reflect.TypeOf(map[string]any(nil))).Interface().(map[string]any), true
+}
+
+// convertMap converts m into the type of like, so that the merged payload has the same type as live.
+func convertMap(like any, m map[string]any) any {
+	return reflect.ValueOf(m).Convert(reflect.TypeOf(like)).Interface()
+}
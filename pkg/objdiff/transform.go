@@ -0,0 +1,74 @@
+package objdiff
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/itchyny/gojq"
+	"k8s.io/apimachinery/pkg/util/json"
+)
+
+// Transform is a jq expression run over the JSON of the objects before they're compared,
+// like `del(.spec.replicas)` or `.spec.template.spec.containers |= map(del(.resources))`.
+type Transform struct {
+	expr string
+	code *gojq.Code
+}
+
+// CompileTransform compiles the jq expression of a Transform so that an invalid one fails before any comparison.
+func CompileTransform(expr string) (*Transform, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid jq expression %q", expr)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid jq expression %q", expr)
+	}
+	return &Transform{expr: expr, code: code}, nil
+}
+
+func (t *Transform) String() string {
+	return t.expr
+}
+
+// Apply returns a copy of obj transformed by the expression, which must yield exactly one object.
+func (t *Transform) Apply(obj *Object) (*Object, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var in map[string]any
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	iter := t.code.Run(in)
+	v, ok := iter.Next()
+	if !ok {
+		return nil, errors.Newf("%q yields nothing for %s", t.expr, obj)
+	}
+	if err, ok := v.(error); ok {
+		return nil, errors.Wrapf(err, "couldn't transform %s by %q", obj, t.expr)
+	}
+	if _, ok := iter.Next(); ok {
+		return nil, errors.Newf("%q yields more than one value for %s", t.expr, obj)
+	}
+	if _, ok := v.(map[string]any); !ok {
+		return nil, errors.Newf("%q yields %s for %s, not an object", t.expr, gojq.TypeOf(v), obj)
+	}
+	data, err = json.Marshal(v)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var out Object
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, errors.Wrapf(err, "%q yields an invalid object for %s", t.expr, obj)
+	}
+	return &out, nil
+}
+
+// WithTransform compares the objects transformed by the Transform on both sides.
+// Objects are matched before they're transformed, and the results still carry the objects as they are.
+func WithTransform(t *Transform) Option {
+	return func(d *Diff) {
+		d.transform = t
+	}
+}
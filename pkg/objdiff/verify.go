@@ -0,0 +1,94 @@
+package objdiff
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// VerifyFailure is the first assertion failed for an object.
+type VerifyFailure struct {
+	Object *Object
+	// Path is the keys joined with dots like "containers.0.image". It's empty if the object is missing.
+	Path    string
+	Message string
+}
+
+func (f VerifyFailure) String() string {
+	if f.Path == "" {
+		return fmt.Sprintf("%s: %s", f.Object, f.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", f.Object, f.Path, f.Message)
+}
+
+// VerifyReport is the result of Verify.
+type VerifyReport struct {
+	Failures []VerifyFailure
+}
+
+// OK tells if every object is reconciled.
+func (r *VerifyReport) OK() bool {
+	return len(r.Failures) == 0
+}
+
+// Verify checks that every local object exists in the cluster and every field set locally matches,
+// while fields existing only in the cluster are fine, which tells if the cluster is reconciled to the manifests.
+// The report has the first failure of each object.
+func (d *Diff) Verify(ctx context.Context, objects []*Object, opts ...cmp.Option) (*VerifyReport, error) {
+	report := &VerifyReport{}
+	for _, obj := range objects {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		failure, err := d.verifyObj(obj, opts...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't verify %s", obj)
+		}
+		if failure != nil {
+			report.Failures = append(report.Failures, *failure)
+		}
+	}
+	return report, nil
+}
+
+func (d *Diff) verifyObj(obj *Object, opts ...cmp.Option) (*VerifyFailure, error) {
+	obj = d.resolveNamespace(obj)
+	mapping, err := d.getResource(obj.APIVersion, obj.Kind)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	converted, err := d.convert(obj, mapping.GroupVersionKind)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	remote, err := d.getRemoteObj(mapping.Resource, converted)
+	if kerrors.IsNotFound(errors.Cause(err)) {
+		return &VerifyFailure{Object: obj, Message: "missing from the cluster"}, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// the local object is always Old regardless of the perspective
+	diffs, err := PerspectiveManifest.DiffObjPaths(converted, remote, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, pd := range diffs {
+		if pd.Old == nil {
+			// only in the cluster
+			continue
+		}
+		if pd.New == nil {
+			return &VerifyFailure{Object: obj, Path: pd.Path, Message: "missing from the cluster"}, nil
+		}
+		message := fmt.Sprintf("%v is expected but %v in the cluster", pd.Old, pd.New)
+		if d.isSensitive(obj) {
+			message = "differs in the cluster"
+		}
+		return &VerifyFailure{Object: obj, Path: pd.Path, Message: message}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,45 @@
+package objdiff
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bitoku/difftool/pkg/util"
+)
+
+// ServerVersion returns the kubernetes version of the cluster.
+func (d *Diff) ServerVersion() (*util.Version, error) {
+	if d.serverVersion != nil {
+		return d.serverVersion, nil
+	}
+	info, err := d.discovery.ServerVersion()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	v, err := util.ParseVersion(info.GitVersion)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	d.serverVersion = v
+	return v, nil
+}
+
+// VersionedOption is an option applied only to the versions matching the constraint.
+// It's applied to any version if the constraint is nil.
+type VersionedOption struct {
+	Constraint *util.Constraint
+	Option     cmp.Option
+	// Name describes the option for NamedOptionsFor
+	Name string
+}
+
+// OptionsFor returns the options applicable to the version.
+func OptionsFor(v *util.Version, opts ...VersionedOption) []cmp.Option {
+	var out []cmp.Option
+	for _, o := range opts {
+		if o.Constraint == nil || o.Constraint.Matches(v) {
+			out = append(out, o.Option)
+		}
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package objdiff
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DiffVersions compares the remote counterparts of obj served at its version and at the other version of the group,
+// like v1beta1 and v1 of a kind before the former is removed, to see the fields changed by the conversion.
+// The one at the version of obj is shown as the manifest, and the results have the ones at the other version.
+// Items of a list are compared at the other version of their own groups.
+// Objects which don't exist are reported as missing, and it's an error if either version isn't served.
+func (d *Diff) DiffVersions(apiVersion, kind string, obj *Object, version string, opts ...cmp.Option) ([]DiffResult, error) {
+	obj = d.resolveNamespace(obj)
+	objs := []*Object{obj}
+	if obj.IsList() {
+		objs = obj.Items
+	}
+	results := []DiffResult{}
+	for _, o := range objs {
+		gvk := schema.FromAPIVersionAndKind(apiVersion, kind)
+		if obj.IsList() && o.Kind != "" {
+			gvk = o.GroupVersionKind()
+		}
+		if isSkipped(o) {
+			results = append(results, DiffResult{Class: ClassSkipped, Object: o, Perspective: d.perspective})
+			continue
+		}
+		r, err := d.diffVersionsOf(o, gvk, version, opts)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		results = append(results, r...)
+	}
+	d.markRedacted(results)
+	return results, nil
+}
+
+func (d *Diff) diffVersionsOf(obj *Object, from schema.GroupVersionKind, version string, opts []cmp.Option) ([]DiffResult, error) {
+	to := from.GroupKind().WithVersion(version)
+	x, err := d.getObjAtVersion(obj, from)
+	if kerrors.IsNotFound(errors.Cause(err)) {
+		return []DiffResult{{Class: ClassMissing, Object: obj, Perspective: d.perspective}}, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	y, err := d.getObjAtVersion(obj, to)
+	if err != nil {
+		// it's an error even if it's not found since it's the same object as the other
+		return nil, errors.WithStack(err)
+	}
+	diff, err := d.diffPair(x, y, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if diff == "" {
+		return nil, nil
+	}
+	return []DiffResult{{Class: ClassChanged, Object: y, Diff: diff, Perspective: d.perspective, ResourceVersion: y.ResourceVersion}}, nil
+}
+
+// getObjAtVersion fetches the remote counterpart of obj at the version.
+func (d *Diff) getObjAtVersion(obj *Object, gvk schema.GroupVersionKind) (*Object, error) {
+	mapping, err := d.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		return nil, errors.Newf("version %s of %s isn't served by the cluster", gvk.Version, gvk.GroupKind())
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	remote, err := d.getRemoteObj(mapping.Resource, obj)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't fetch %s at %s", obj, gvk.GroupVersion())
+	}
+	return remote, nil
+}
@@ -0,0 +1,89 @@
+package objdiff
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/dynamic"
+)
+
+// Watch calls fn with the results of Diff every time the remote objects compared with obj change,
+// until ctx is done or fn returns an error. fn is called once the watch starts as well.
+// Changes notified while fn is running are coalesced into one call.
+func (d *Diff) Watch(ctx context.Context, apiVersion, kind string, obj *Object, fn func([]DiffResult) error, opts ...cmp.Option) error {
+	mapping, err := d.getResource(apiVersion, kind)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	obj = d.resolveNamespace(obj)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+
+	if obj.IsList() {
+		mappings, _, err := d.listMappings(mapping, obj.Items)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		for _, m := range mappings {
+			go d.watchResource(ctx, d.client.Resource(m.Resource), v1.ListOptions{}, changed, errCh)
+		}
+	} else {
+		var ri dynamic.ResourceInterface = d.client.Resource(mapping.Resource)
+		if obj.Namespace != "" {
+			ri = d.client.Resource(mapping.Resource).Namespace(obj.Namespace)
+		}
+		listOpts := v1.ListOptions{FieldSelector: fields.OneTermEqualSelector("metadata.name", obj.Name).String()}
+		go d.watchResource(ctx, ri, listOpts, changed, errCh)
+	}
+
+	// the watch may not notify anything when the object doesn't exist
+	notify(changed)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errCh:
+			return errors.WithStack(err)
+		case <-changed:
+			results, err := d.Diff(apiVersion, kind, obj, opts...)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if err := fn(results); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+}
+
+// watchResource notifies changed of every event of the resource and rewatches when the watch is closed by the server.
+func (d *Diff) watchResource(ctx context.Context, ri dynamic.ResourceInterface, opts v1.ListOptions, changed chan<- struct{}, errCh chan<- error) {
+	for ctx.Err() == nil {
+		w, err := ri.Watch(ctx, opts)
+		if err != nil {
+			select {
+			case errCh <- errors.WithStack(err):
+			case <-ctx.Done():
+			}
+			return
+		}
+		for range w.ResultChan() {
+			notify(changed)
+		}
+		w.Stop()
+	}
+}
+
+func notify(ch chan<- struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,84 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/util/json"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+)
+
+// DiffReport accumulates the results of multiple sources like directories or clusters into one report,
+// which is rendered grouped by the sources in the order they're added first. The zero value is ready to use,
+// and it's safe to add results concurrently.
+type DiffReport struct {
+	mu      sync.Mutex
+	sources []string
+	results map[string][]objdiff.DiffResult
+}
+
+// Add adds the results of the source, appended to the ones added before for the same source.
+func (r *DiffReport) Add(source string, results []objdiff.DiffResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.results == nil {
+		r.results = make(map[string][]objdiff.DiffResult)
+	}
+	if _, ok := r.results[source]; !ok {
+		r.sources = append(r.sources, source)
+	}
+	r.results[source] = append(r.results[source], results...)
+}
+
+// Sources returns the sources in the order they're added first.
+func (r *DiffReport) Sources() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.sources...)
+}
+
+// Results returns the results of the source.
+func (r *DiffReport) Results(source string) []objdiff.DiffResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]objdiff.DiffResult(nil), r.results[source]...)
+}
+
+type jsonSource struct {
+	Source  string       `json:"source"`
+	Results []jsonResult `json:"results"`
+}
+
+// Render renders the results grouped by the sources. Plain and markdown reports have a heading for each source,
+// and json reports are an array of the sources with their results. Scripts can't be grouped.
+// The results of plain and markdown reports are formatted by f.
+func (r *DiffReport) Render(format Format, f *objdiff.Formatter) ([]byte, error) {
+	var b strings.Builder
+	switch format {
+	case FormatPlain:
+		for _, s := range r.Sources() {
+			fmt.Fprintf(&b, "# %s\n%s\n", s, RenderPlain(f, r.Results(s)))
+		}
+		return []byte(b.String()), nil
+	case FormatMarkdown:
+		for _, s := range r.Sources() {
+			fmt.Fprintf(&b, "## %s\n\n%s", escapeMarkdown(s), RenderMarkdown(f, r.Results(s)))
+		}
+		return []byte(b.String()), nil
+	case FormatJSON:
+		sources := r.Sources()
+		out := make([]jsonSource, 0, len(sources))
+		for _, s := range sources {
+			out = append(out, jsonSource{Source: s, Results: jsonResults(r.Results(s))})
+		}
+		data, err := json.Marshal(out)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%q reports can't be grouped by sources", format)
+}
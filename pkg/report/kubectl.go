@@ -0,0 +1,254 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/yaml"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+)
+
+// kubectlContext is the number of unchanged lines around the changes like `diff -u`.
+const kubectlContext = 3
+
+// RenderKubectlDiff renders the results like `kubectl diff`, which is `diff -u -N` between the yaml of the live objects
+// and of the merged ones in the temporary directories, so that the tools parsing its output can read the results.
+// The merged object is the live one with the fields of the manifest merged, where the maps are merged
+// and anything else is replaced. It needs the remote objects of the changed results of objdiff.WithRemoteObjects,
+// and the changed results without them are left out. The values of sensitive objects are masked like kubectl does,
+// and their last applied configurations are dropped since they have the values in plain text.
+func RenderKubectlDiff(results []objdiff.DiffResult) ([]byte, error) {
+	live, merged := filepath.Join(os.TempDir(), "LIVE-difftool"), filepath.Join(os.TempDir(), "MERGED-difftool")
+	now := time.Now()
+	var b strings.Builder
+	for _, r := range results {
+		var before, after map[string]any
+		var err error
+		switch r.Class {
+		case objdiff.ClassChanged:
+			if r.Remote == nil {
+				continue
+			}
+			before, err = objectMap(redactedObject(r, r.Remote))
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			local, err := objectMap(redactedObject(r, r.Object))
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			after = mergeMaps(before, local)
+		case objdiff.ClassMissing:
+			after, err = objectMap(redactedObject(r, r.Object))
+		case objdiff.ClassExtra:
+			before, err = objectMap(redactedObject(r, r.Object))
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if r.Redacted {
+			maskValues(before, after)
+		}
+		x, err := objectYAML(before)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		y, err := objectYAML(after)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		name := kubectlName(r.Object)
+		from, to := filepath.Join(live, name), filepath.Join(merged, name)
+		fmt.Fprintf(&b, "diff -u -N %s %s\n", from, to)
+		fmt.Fprintf(&b, "--- %s\t%s\n+++ %s\t%s\n", from, now.Format(diffTimeLayout), to, now.Format(diffTimeLayout))
+		b.WriteString(unifiedHunks(x, y, kubectlContext))
+	}
+	return []byte(b.String()), nil
+}
+
+// diffTimeLayout is the layout of the times of the files of GNU diff.
+const diffTimeLayout = "2006-01-02 15:04:05.000000000 -0700"
+
+// kubectlName returns the name of the file of the object like "apps.v1.Deployment.default.web" as kubectl names it.
+func kubectlName(obj *objdiff.Object) string {
+	gvk := obj.GroupVersionKind()
+	name := fmt.Sprintf("%s.%s.%s.%s", gvk.Version, gvk.Kind, obj.Namespace, obj.Name)
+	if gvk.Group != "" {
+		name = gvk.Group + "." + name
+	}
+	return name
+}
+
+// redactedObject returns the object of the result without the last applied configuration if the result is redacted.
+func redactedObject(r objdiff.DiffResult, obj *objdiff.Object) *objdiff.Object {
+	if !r.Redacted {
+		return obj
+	}
+	return objdiff.WithoutLastApplied(obj)
+}
+
+// objectMap returns the object as a map without the managedFields, which kubectl doesn't show by default,
+// and the null metadata like creationTimestamp of the manifests.
+func objectMap(obj *objdiff.Object) (map[string]any, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if metadata, ok := out["metadata"].(map[string]any); ok {
+		delete(metadata, "managedFields")
+		for k, v := range metadata {
+			if v == nil {
+				delete(metadata, k)
+			}
+		}
+	}
+	return out, nil
+}
+
+// objectYAML renders the object, which is empty if it's nil like a file which doesn't exist for `diff -N`.
+func objectYAML(obj map[string]any) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return string(data), nil
+}
+
+// mergeMaps returns a copy of x with the entries of y merged recursively, where nulls in y are ignored.
+func mergeMaps(x, y map[string]any) map[string]any {
+	out := make(map[string]any, len(x))
+	for k, v := range x {
+		out[k] = v
+	}
+	for k, v := range y {
+		if v == nil {
+			continue
+		}
+		xm, okx := out[k].(map[string]any)
+		ym, oky := v.(map[string]any)
+		if okx && oky {
+			out[k] = mergeMaps(xm, ym)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// maskValues replaces the values of the payloads with "***" like kubectl does for Secrets,
+// which are marked "(before)" and "(after)" if they differ.
+func maskValues(before, after map[string]any) {
+	for _, field := range []string{"spec", "data", "binaryData", "stringData", "status"} {
+		x, _ := before[field].(map[string]any)
+		y, inY := after[field].(map[string]any)
+		for k := range x {
+			if inY && reflect.DeepEqual(x[k], y[k]) {
+				x[k], y[k] = "***", "***"
+				continue
+			}
+			x[k] = "*** (before)"
+		}
+		for k, v := range y {
+			if v != "***" {
+				y[k] = "*** (after)"
+			}
+		}
+	}
+}
+
+type diffLine struct {
+	op   diffmatchpatch.Operation
+	text string
+}
+
+// unifiedHunks renders the hunks of the unified diff from x to y with the number of the lines of the context.
+func unifiedHunks(x, y string, context int) string {
+	var lines []diffLine
+	for _, d := range diff.Do(x, y) {
+		for _, l := range strings.SplitAfter(d.Text, "\n") {
+			if l != "" {
+				lines = append(lines, diffLine{op: d.Type, text: l})
+			}
+		}
+	}
+	var b strings.Builder
+	// the lines of x and y before lines[i]
+	xs, ys := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	for i, l := range lines {
+		xs[i+1], ys[i+1] = xs[i], ys[i]
+		if l.op != diffmatchpatch.DiffInsert {
+			xs[i+1]++
+		}
+		if l.op != diffmatchpatch.DiffDelete {
+			ys[i+1]++
+		}
+	}
+	for i := 0; i < len(lines); {
+		if lines[i].op == diffmatchpatch.DiffEqual {
+			i++
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		// extend the hunk while the next change is within twice the context
+		end, equal := i, 0
+		for end < len(lines) && equal <= 2*context {
+			if lines[end].op == diffmatchpatch.DiffEqual {
+				equal++
+			} else {
+				equal = 0
+			}
+			end++
+		}
+		if equal > context {
+			end -= equal - context
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(xs[start], xs[end]-xs[start]), hunkRange(ys[start], ys[end]-ys[start]))
+		for _, l := range lines[start:end] {
+			sign := " "
+			switch l.op {
+			case diffmatchpatch.DiffDelete:
+				sign = "-"
+			case diffmatchpatch.DiffInsert:
+				sign = "+"
+			}
+			b.WriteString(sign + l.text)
+			if !strings.HasSuffix(l.text, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// hunkRange formats the range of the lines from start like `diff -u`, where an empty range is at the line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,78 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+)
+
+func secret(data map[string]any, annotations map[string]string) *objdiff.Object {
+	return &objdiff.Object{
+		TypeMeta:   v1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "creds", Annotations: annotations},
+		Data:       data,
+	}
+}
+
+func TestRenderKubectlDiffRedactsSecrets(t *testing.T) {
+	lastApplied := `{"apiVersion":"v1","kind":"Secret","data":{"password":"c2VjcmV0"},"metadata":{"name":"creds"}}`
+	annotations := map[string]string{objdiff.LastAppliedAnnotation: lastApplied, "owner": "team-a"}
+	tests := []struct {
+		name   string
+		result objdiff.DiffResult
+	}{
+		{
+			name:   "extra",
+			result: objdiff.DiffResult{Class: objdiff.ClassExtra, Object: secret(map[string]any{"password": "c2VjcmV0"}, annotations), Redacted: true},
+		},
+		{
+			name:   "missing",
+			result: objdiff.DiffResult{Class: objdiff.ClassMissing, Object: secret(map[string]any{"password": "c2VjcmV0"}, annotations), Redacted: true},
+		},
+		{
+			name: "changed",
+			result: objdiff.DiffResult{
+				Class:    objdiff.ClassChanged,
+				Object:   secret(map[string]any{"password": "c2VjcmV0"}, annotations),
+				Remote:   secret(map[string]any{"password": "b2xk"}, map[string]string{objdiff.LastAppliedAnnotation: lastApplied}),
+				Redacted: true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := RenderKubectlDiff([]objdiff.DiffResult{tt.result})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, leaked := range []string{"c2VjcmV0", "b2xk", objdiff.LastAppliedAnnotation} {
+				if strings.Contains(string(out), leaked) {
+					t.Errorf("the diff has %q:\n%s", leaked, out)
+				}
+			}
+			if !strings.Contains(string(out), "owner: team-a") {
+				t.Errorf("the diff lost the other annotations:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestRenderKubectlDiffKeepsLastAppliedOfOthers(t *testing.T) {
+	obj := &objdiff.Object{
+		TypeMeta:   v1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "settings", Annotations: map[string]string{objdiff.LastAppliedAnnotation: "{}"}},
+		Data:       map[string]any{"level": "debug"},
+	}
+	out, err := RenderKubectlDiff([]objdiff.DiffResult{{Class: objdiff.ClassExtra, Object: obj}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{objdiff.LastAppliedAnnotation, "level: debug"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("the diff doesn't have %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/yaml"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+)
+
+// Format is the format of a report.
+type Format string
+
+const (
+	FormatPlain    Format = "plain"
+	FormatJSON     Format = "json"
+	FormatMarkdown Format = "markdown"
+	FormatScript   Format = "script"
+	FormatKubectl  Format = "kubectl"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatPlain, FormatJSON, FormatMarkdown, FormatScript, FormatKubectl:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q", s)
+}
+
+// FormatFromPath infers the format from the extension of the path.
+// It's FormatPlain unless the extension is known.
+func FormatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".md", ".markdown":
+		return FormatMarkdown
+	case ".sh":
+		return FormatScript
+	case ".diff", ".patch":
+		return FormatKubectl
+	}
+	return FormatPlain
+}
+
+// Render renders the results in the format, where the results of plain and markdown reports are formatted by f.
+func Render(format Format, f *objdiff.Formatter, results []objdiff.DiffResult) ([]byte, error) {
+	switch format {
+	case FormatPlain:
+		return []byte(RenderPlain(f, results)), nil
+	case FormatJSON:
+		return RenderJSON(results)
+	case FormatMarkdown:
+		return []byte(RenderMarkdown(f, results)), nil
+	case FormatScript:
+		return RenderScript(results, false)
+	case FormatKubectl:
+		return RenderKubectlDiff(results)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func RenderPlain(f *objdiff.Formatter, results []objdiff.DiffResult) string {
+	var b strings.Builder
+	for _, r := range results {
+		b.WriteString(f.Format(r))
+		if r.Class == objdiff.ClassChanged {
+			b.WriteString(RemoteYAML(r))
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// RemoteYAML renders the remote object attached to the result headed by "# remote", or nothing if there's none.
+func RemoteYAML(r objdiff.DiffResult) string {
+	if r.Remote == nil {
+		return ""
+	}
+	data, err := yaml.Marshal(r.Remote)
+	if err != nil {
+		return fmt.Sprintf("# remote\n# couldn't render: %v\n", err)
+	}
+	return "# remote\n" + string(data)
+}
+
+type jsonResult struct {
+	APIVersion      string          `json:"apiVersion"`
+	Kind            string          `json:"kind"`
+	Namespace       string          `json:"namespace,omitempty"`
+	Name            string          `json:"name"`
+	Class           objdiff.Class   `json:"class"`
+	Diff            string          `json:"diff,omitempty"`
+	ResourceVersion string          `json:"resourceVersion,omitempty"`
+	Remote          *objdiff.Object `json:"remote,omitempty"`
+	DriftScore      float64         `json:"driftScore,omitempty"`
+}
+
+func RenderJSON(results []objdiff.DiffResult) ([]byte, error) {
+	data, err := json.Marshal(jsonResults(results))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return data, nil
+}
+
+func jsonResults(results []objdiff.DiffResult) []jsonResult {
+	out := make([]jsonResult, 0, len(results))
+	for _, r := range results {
+		out = append(out, jsonResult{
+			APIVersion:      r.Object.APIVersion,
+			Kind:            r.Object.Kind,
+			Namespace:       r.Object.Namespace,
+			Name:            r.Object.Name,
+			Class:           r.Class,
+			Diff:            r.Diff,
+			ResourceVersion: r.ResourceVersion,
+			Remote:          r.Remote,
+			DriftScore:      r.DriftScore,
+		})
+	}
+	return out
+}
+
+// RenderMarkdown renders a summary table of the classes followed by a collapsible section for each result,
+// so that large reports fit in a PR comment. The results other than changed ones are formatted by f.
+func RenderMarkdown(f *objdiff.Formatter, results []objdiff.DiffResult) string {
+	counts := make(map[objdiff.Class]int)
+	for _, r := range results {
+		counts[r.Class]++
+	}
+
+	var b strings.Builder
+	b.WriteString("| Class | Count |\n| --- | ---: |\n")
+	for _, c := range []objdiff.Class{objdiff.ClassChanged, objdiff.ClassMissing, objdiff.ClassExtra, objdiff.ClassTerminating, objdiff.ClassForbidden, objdiff.ClassSkipped, objdiff.ClassProgressing, objdiff.ClassRejected} {
+		fmt.Fprintf(&b, "| %s | %d |\n", c, counts[c])
+	}
+	b.WriteString("\n")
+
+	for _, r := range results {
+		// summary is html, so markdown isn't interpreted there
+		class := string(r.Class)
+		if r.DriftScore > 0 {
+			class += fmt.Sprintf(", drift %g", r.DriftScore)
+		}
+		fmt.Fprintf(&b, "<details>\n<summary>%s (%s)</summary>\n\n", html.EscapeString(r.Object.String()), class)
+		if r.Class == objdiff.ClassChanged {
+			fence := codeFence(r.Diff)
+			fmt.Fprintf(&b, "%sdiff\n%s%s\n", fence, r.Diff, fence)
+		} else {
+			b.WriteString(escapeMarkdown(f.Format(r)))
+		}
+		b.WriteString("\n</details>\n\n")
+	}
+	return b.String()
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", "&lt;", ">", "&gt;", "|", `\|`, "#", `\#`,
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// codeFence returns a fence longer than any run of backticks in s, so that s can't close the block.
+func codeFence(s string) string {
+	longest, run := 0, 0
+	for _, c := range s {
+		if c != '`' {
+			run = 0
+			continue
+		}
+		run++
+		if run > longest {
+			longest = run
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
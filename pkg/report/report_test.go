@@ -0,0 +1,32 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+)
+
+func TestRenderFormatter(t *testing.T) {
+	obj := &objdiff.Object{
+		TypeMeta:   v1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "settings"},
+	}
+	results := []objdiff.DiffResult{{Class: objdiff.ClassMissing, Object: obj}, {Class: objdiff.ClassExtra, Object: obj}}
+	f := objdiff.MustFormatter("{{.Object.Name}} is to be created", "{{.Object.Name}} is to be pruned")
+	for _, format := range []Format{FormatPlain, FormatMarkdown} {
+		t.Run(string(format), func(t *testing.T) {
+			out, err := Render(format, f, results)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []string{"settings is to be created", "settings is to be pruned"} {
+				if !strings.Contains(string(out), want) {
+					t.Errorf("the report doesn't have %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
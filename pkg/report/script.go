@@ -0,0 +1,58 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/util/json"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+)
+
+// scriptDelimiter ends the here documents of the manifests in scripts.
+const scriptDelimiter = "MANIFEST"
+
+// RenderScript renders a shell script reconciling the cluster to the manifests,
+// which applies the changed and missing objects and, if deleteExtra is true, deletes the extra ones.
+// It's meant to be reviewed before being run since the objects carry only their metadata, spec and data.
+// Terminating objects are left to their finalizers, and redacted objects are left to be applied by hand.
+func RenderScript(results []objdiff.DiffResult, deleteExtra bool) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\nset -e\n")
+	for _, r := range results {
+		switch r.Class {
+		case objdiff.ClassChanged, objdiff.ClassMissing:
+			if r.Redacted {
+				// the manifest would leak the secrets into the script
+				fmt.Fprintf(&b, "\n# %s (%s)\n# sensitive values are redacted. apply its manifest instead\n", r.Object, r.Class)
+				continue
+			}
+			manifest, err := json.Marshal(r.Object)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			fmt.Fprintf(&b, "\n# %s (%s)\nkubectl apply -f - <<'%s'\n%s\n%s\n", r.Object, r.Class, scriptDelimiter, manifest, scriptDelimiter)
+		case objdiff.ClassExtra:
+			if !deleteExtra {
+				continue
+			}
+			fmt.Fprintf(&b, "\n# %s (%s)\nkubectl delete %s\n", r.Object, r.Class, deleteArgs(r.Object))
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// deleteArgs returns the arguments of kubectl delete fully qualified by the version and the group like "deployment.v1.apps name".
+func deleteArgs(obj *objdiff.Object) string {
+	gvk := obj.GroupVersionKind()
+	resource := strings.ToLower(gvk.Kind)
+	if gvk.Group != "" {
+		resource = strings.Join([]string{resource, gvk.Version, gvk.Group}, ".")
+	}
+	args := resource + " " + obj.Name
+	if obj.Namespace != "" {
+		args += " -n " + obj.Namespace
+	}
+	return args
+}
@@ -0,0 +1,62 @@
+package report
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+)
+
+// Templates is the built-in templates for RenderTemplate by name.
+var Templates = map[string]string{
+	"oneline":  "{{.Class}} {{.Identity}}\n",
+	"detailed": "{{.Class}}: {{.Identity}}{{if .ResourceVersion}} (resourceVersion {{.ResourceVersion}}){{end}}\n{{if .Diff}}{{.Diff}}\n{{end}}",
+}
+
+// templateData is given to templates for each result.
+type templateData struct {
+	APIVersion      string
+	Kind            string
+	Namespace       string
+	Name            string
+	Identity        string
+	Class           objdiff.Class
+	Diff            string
+	ResourceVersion string
+}
+
+// ParseTemplate parses a text/template of a result, or returns the built-in one of the name.
+func ParseTemplate(s string) (*template.Template, error) {
+	if builtin, ok := Templates[s]; ok {
+		s = builtin
+	}
+	t, err := template.New("result").Parse(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't parse the template")
+	}
+	return t, nil
+}
+
+// RenderTemplate renders each result with the template,
+// which is given the identity, the class and the diff of the result among the others.
+func RenderTemplate(t *template.Template, results []objdiff.DiffResult) (string, error) {
+	var b strings.Builder
+	for _, r := range results {
+		data := templateData{
+			APIVersion:      r.Object.APIVersion,
+			Kind:            r.Object.Kind,
+			Namespace:       r.Object.Namespace,
+			Name:            r.Object.Name,
+			Identity:        r.Object.String(),
+			Class:           r.Class,
+			Diff:            r.Diff,
+			ResourceVersion: r.ResourceVersion,
+		}
+		if err := t.Execute(&b, data); err != nil {
+			return "", errors.Wrapf(err, "couldn't render %s", r.Object)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+)
+
+// Verdict is what `kubectl apply` would do to an object, in its wording.
+type Verdict string
+
+const (
+	VerdictCreated    Verdict = "created"
+	VerdictConfigured Verdict = "configured"
+	VerdictUnchanged  Verdict = "unchanged"
+)
+
+// ObjectVerdict is the verdict of a local object.
+type ObjectVerdict struct {
+	Object  *objdiff.Object
+	Verdict Verdict
+}
+
+// Verdicts tells what applying each of the local objects would do by the results of them,
+// which is created if it's missing, configured if it's changed and unchanged otherwise.
+// Objects which couldn't be compared since the access is denied or which the server rejects to create have no verdicts.
+func Verdicts(local []*objdiff.Object, results []objdiff.DiffResult) []ObjectVerdict {
+	classes := make(map[string]objdiff.Class, len(results))
+	for _, r := range results {
+		if r.Class == objdiff.ClassExtra || r.Class == objdiff.ClassTerminating || r.Class == objdiff.ClassProgressing {
+			continue
+		}
+		classes[verdictKey(r.Object, r.Object.Namespace)] = r.Class
+		// the namespace of a local object may be resolved by the diff
+		classes[verdictKey(r.Object, "")] = r.Class
+	}
+	out := make([]ObjectVerdict, 0, len(local))
+	for _, o := range local {
+		verdict := VerdictUnchanged
+		switch classes[verdictKey(o, o.Namespace)] {
+		case objdiff.ClassMissing:
+			verdict = VerdictCreated
+		case objdiff.ClassChanged:
+			verdict = VerdictConfigured
+		case objdiff.ClassForbidden, objdiff.ClassSkipped, objdiff.ClassRejected:
+			continue
+		}
+		out = append(out, ObjectVerdict{Object: o, Verdict: verdict})
+	}
+	return out
+}
+
+// verdictKey identifies an object regardless of its version since the results may carry the converted objects.
+func verdictKey(obj *objdiff.Object, namespace string) string {
+	return strings.Join([]string{obj.GroupVersionKind().GroupKind().String(), namespace, obj.Name}, "/")
+}
+
+// RenderVerdicts renders the verdicts as `kubectl apply --dry-run` prints like "deployment.apps/web configured (dry run)".
+func RenderVerdicts(verdicts []ObjectVerdict) string {
+	var b strings.Builder
+	for _, v := range verdicts {
+		fmt.Fprintf(&b, "%s/%s %s (dry run)\n", strings.ToLower(v.Object.GroupVersionKind().GroupKind().String()), v.Object.Name, v.Verdict)
+	}
+	return b.String()
+}
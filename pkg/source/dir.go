@@ -0,0 +1,54 @@
+package source
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+)
+
+// LoadDir loads the objects in the yaml and json files under the directory in the order of their paths,
+// where multiple documents in a file and the items of lists are separate objects.
+// The sidecars of the files are applied to their objects.
+func LoadDir(dir string) ([]*objdiff.Object, error) {
+	var objs []*objdiff.Object
+	err := filepath.WalkDir(dir, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		if e.IsDir() || IsSidecar(path) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		docs, err := SplitObjects(data)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't parse %s", path)
+		}
+		if err := loadSidecar(path, docs); err != nil {
+			return errors.WithStack(err)
+		}
+		for _, d := range docs {
+			if d.IsList() {
+				objs = append(objs, d.Items...)
+				continue
+			}
+			objs = append(objs, d)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return objs, nil
+}
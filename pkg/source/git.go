@@ -0,0 +1,93 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// ExportRef writes the files under dir of the git repository at the ref into dest, without touching the working tree.
+// The repository is either a local path or a url cloned in memory.
+// The ref is anything git rev-parse takes, like "main", "v1.0.0" or a commit hash.
+func ExportRef(repository, ref, dir, dest string) error {
+	tree, err := treeAt(repository, ref)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if dir = strings.Trim(filepath.ToSlash(dir), "/"); dir != "" && dir != "." {
+		tree, err = tree.Tree(dir)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't find %s at %s", dir, ref)
+		}
+	}
+
+	return tree.Files().ForEach(func(f *object.File) error {
+		content, err := f.Contents()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		path := filepath.Join(dest, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return errors.WithStack(err)
+		}
+		return errors.WithStack(os.WriteFile(path, []byte(content), 0o644))
+	})
+}
+
+// ReadFileAt returns the content of the file in the git repository at the ref.
+func ReadFileAt(repository, ref, path string) ([]byte, error) {
+	tree, err := treeAt(repository, ref)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	f, err := tree.File(strings.Trim(filepath.ToSlash(path), "/"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't find %s at %s", path, ref)
+	}
+	content, err := f.Contents()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return []byte(content), nil
+}
+
+func treeAt(repository, ref string) (*object.Tree, error) {
+	repo, err := openRepository(repository)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't resolve %s", ref)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return tree, nil
+}
+
+func openRepository(repository string) (*git.Repository, error) {
+	if strings.Contains(repository, "://") || strings.HasPrefix(repository, "git@") {
+		repo, err := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{URL: repository})
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't clone %s", repository)
+		}
+		return repo, nil
+	}
+	repo, err := git.PlainOpenWithOptions(repository, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't open %s", repository)
+	}
+	return repo, nil
+}
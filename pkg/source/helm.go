@@ -0,0 +1,114 @@
+package source
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/util/json"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+)
+
+// HelmRelease is the part of a release stored in a release secret of helm.
+type HelmRelease struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Version   int    `json:"version"`
+	Manifest  string `json:"manifest"`
+}
+
+// gzipMagic is the header of gzipped data, which helm compresses the releases with.
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// DecodeHelmRelease decodes the value of the release key of a release secret,
+// which helm encodes as base64 of the gzipped json on top of the base64 of the secret data.
+func DecodeHelmRelease(value string) (*HelmRelease, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't decode the secret data")
+	}
+	data, err = base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't decode the release")
+	}
+	if bytes.HasPrefix(data, gzipMagic) {
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't decompress the release")
+		}
+		data, err = io.ReadAll(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't decompress the release")
+		}
+	}
+	var release HelmRelease
+	if err := json.Unmarshal(data, &release); err != nil {
+		return nil, errors.Wrap(err, "couldn't parse the release")
+	}
+	return &release, nil
+}
+
+// Objects parses the manifest of the release into objects.
+func (r *HelmRelease) Objects() ([]*objdiff.Object, error) {
+	objs, err := SplitObjects([]byte(r.Manifest))
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't parse the manifest of the release %s", r.Name)
+	}
+	return objs, nil
+}
+
+// LoadHelmRelease reads the manifest of the latest deployed revision of the release in the namespace
+// from the release secrets in the cluster. Namespaced objects without namespaces are in the namespace of the release
+// since helm installs them there.
+func LoadHelmRelease(d *objdiff.Diff, namespace, release string) ([]*objdiff.Object, error) {
+	selector := fmt.Sprintf("owner=helm,name=%s,status=deployed", release)
+	secrets, err := d.ListSelected("v1", "Secret", namespace, selector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't list the secrets of the release %s", release)
+	}
+	var latest *objdiff.Object
+	latestVersion := -1
+	for _, s := range secrets {
+		v, err := strconv.Atoi(s.Labels["version"])
+		if err != nil {
+			continue
+		}
+		if v > latestVersion {
+			latest, latestVersion = s, v
+		}
+	}
+	if latest == nil {
+		return nil, errors.Newf("no deployed release %s in %s", release, namespace)
+	}
+	data, _ := latest.Data.(map[string]any)
+	value, ok := data["release"].(string)
+	if !ok {
+		return nil, errors.Newf("%s has no release", latest)
+	}
+	r, err := DecodeHelmRelease(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't decode %s", latest)
+	}
+	objs, err := r.Objects()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, o := range objs {
+		if o.Namespace != "" {
+			continue
+		}
+		namespaced, err := d.IsNamespaced(o.APIVersion, o.Kind)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't resolve the kind of %s", o)
+		}
+		if namespaced {
+			o.Namespace = namespace
+		}
+	}
+	return objs, nil
+}
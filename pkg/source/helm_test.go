@@ -0,0 +1,99 @@
+package source
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"testing"
+)
+
+const helmManifest = `---
+# Source: app/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  level: debug
+---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+spec:
+  ports:
+  - port: 80
+`
+
+// encodeHelmRelease encodes the release json like helm does, gzipped if zipped, as the value of a release secret.
+func encodeHelmRelease(t *testing.T, release string, zipped bool) string {
+	t.Helper()
+	data := []byte(release)
+	if zipped {
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		data = b.Bytes()
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+	return base64.StdEncoding.EncodeToString([]byte(encoded))
+}
+
+func TestDecodeHelmRelease(t *testing.T) {
+	release := fmt.Sprintf(`{"name": "app", "namespace": "apps", "version": 3, "manifest": %q}`, helmManifest)
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "gzipped", value: encodeHelmRelease(t, release, true)},
+		{name: "plain", value: encodeHelmRelease(t, release, false)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := DecodeHelmRelease(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Name != "app" || r.Namespace != "apps" || r.Version != 3 {
+				t.Errorf("release = %+v", r)
+			}
+			objs, err := r.Objects()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, o := range objs {
+				got = append(got, o.Kind+" "+o.Name)
+			}
+			if fmt.Sprint(got) != "[ConfigMap app Service app]" {
+				t.Errorf("objects = %v", got)
+			}
+		})
+	}
+}
+
+func TestDecodeHelmReleaseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "bad secret data", value: "not base64!"},
+		{name: "bad release", value: base64.StdEncoding.EncodeToString([]byte("not base64!"))},
+		{name: "bad gzip", value: encodeHelmRelease(t, "\x1f\x8b\x08broken", false)},
+		{name: "bad json", value: encodeHelmRelease(t, "{", true)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if r, err := DecodeHelmRelease(tt.value); err == nil {
+				t.Errorf("DecodeHelmRelease() = %+v, want an error", r)
+			}
+		})
+	}
+}
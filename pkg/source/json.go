@@ -0,0 +1,67 @@
+package source
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"io"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/util/json"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+)
+
+// isJSON tells if the data is json rather than yaml by its first character,
+// since yaml documents of objects never start with a brace or a bracket.
+func isJSON(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) != 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// SplitJSONObjects decodes a stream of json documents into objects like the output of jsonnet or cdk8s,
+// where a document is an object or an array of objects.
+// The documents are decoded as SplitObjects does so that int64 isn't inferred as float64.
+func SplitJSONObjects(data []byte) ([]*objdiff.Object, error) {
+	dec := stdjson.NewDecoder(bytes.NewReader(data))
+	var objs []*objdiff.Object
+	for {
+		var doc stdjson.RawMessage
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if bytes.HasPrefix(bytes.TrimSpace(doc), []byte("[")) {
+			var items []stdjson.RawMessage
+			if err := json.Unmarshal(doc, &items); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			for _, item := range items {
+				obj, err := decodeJSONObject(item)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				objs = append(objs, obj)
+			}
+			continue
+		}
+		if string(bytes.TrimSpace(doc)) == "null" {
+			continue
+		}
+		obj, err := decodeJSONObject(doc)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		objs = append(objs, obj)
+	}
+}
+
+func decodeJSONObject(data []byte) (*objdiff.Object, error) {
+	obj := new(objdiff.Object)
+	if err := json.Unmarshal(data, obj); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return obj, nil
+}
@@ -0,0 +1,138 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+)
+
+// Renderer renders the manifests in a directory into yaml documents.
+type Renderer interface {
+	Render(dir string) ([]byte, error)
+}
+
+// Kustomize renders a kustomization like `kustomize build`.
+type Kustomize struct{}
+
+func (Kustomize) Render(dir string) ([]byte, error) {
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resources, err := k.Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't build the kustomization in %s", dir)
+	}
+	out, err := resources.AsYaml()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return out, nil
+}
+
+// Helm renders a chart like `helm template`.
+// It runs the helm command since the helm library would pull in far more dependencies than the rest of the tool.
+type Helm struct {
+	Release    string
+	Namespace  string
+	ValueFiles []string
+}
+
+func (h Helm) Render(dir string) ([]byte, error) {
+	release := h.Release
+	if release == "" {
+		release = "release"
+	}
+	args := []string{"template", release, dir}
+	if h.Namespace != "" {
+		args = append(args, "--namespace", h.Namespace)
+	}
+	for _, f := range h.ValueFiles {
+		args = append(args, "--values", f)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("helm", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't render the chart in %s: %s", dir, stderr.String())
+	}
+	return out, nil
+}
+
+// ParseRenderer returns the renderer of the name: kustomize or helm.
+func ParseRenderer(name string) (Renderer, error) {
+	switch name {
+	case "kustomize":
+		return Kustomize{}, nil
+	case "helm":
+		return Helm{}, nil
+	}
+	return nil, fmt.Errorf("unknown renderer %q", name)
+}
+
+// SplitObjects decodes the yaml documents into objects, skipping empty documents.
+// The data is decoded by SplitJSONObjects if it's json.
+func SplitObjects(data []byte) ([]*objdiff.Object, error) {
+	if isJSON(data) {
+		return SplitJSONObjects(data)
+	}
+	reader := yaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	var objs []*objdiff.Object
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// convert yaml to json first so that int64 isn't inferred as float64
+		jsonContent, err := yaml.ToJSON(doc)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if len(bytes.TrimSpace(jsonContent)) == 0 || string(bytes.TrimSpace(jsonContent)) == "null" {
+			continue
+		}
+		obj := new(objdiff.Object)
+		if err := json.Unmarshal(jsonContent, obj); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		objs = append(objs, obj)
+	}
+}
+
+// SelectObjects selects the documents of the objects by the index among them if it's not negative,
+// and by the kind and the name if they're given, so that one object of a bundle can be compared alone.
+// The kind matches regardless of the case, and it fails if no document is selected.
+func SelectObjects(objs []*objdiff.Object, index int, kind, name string) ([]*objdiff.Object, error) {
+	if index >= 0 {
+		if index >= len(objs) {
+			return nil, errors.Newf("no document at %d since there are %d documents", index, len(objs))
+		}
+		objs = objs[index : index+1]
+	}
+	var selected []*objdiff.Object
+	for _, o := range objs {
+		if kind != "" && !strings.EqualFold(o.Kind, kind) {
+			continue
+		}
+		if name != "" && o.Name != name {
+			continue
+		}
+		selected = append(selected, o)
+	}
+	if len(selected) == 0 {
+		return nil, errors.Newf("no document of kind %q and name %q", kind, name)
+	}
+	return selected, nil
+}
@@ -0,0 +1,93 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/bitoku/difftool/pkg/objdiff"
+)
+
+// DirSidecar is the name of the sidecar of every manifest in its directory.
+const DirSidecar = ".difftool.yaml"
+
+// Sidecar is the options of the objects in a manifest, read from the file next to it named like dns.difftool.yaml
+// for dns.yaml, or DirSidecar for the manifests in the directory without their own.
+type Sidecar struct {
+	// Ignore is the keys of the payloads ignored like objdiff.IgnoreAnnotation
+	Ignore []string `json:"ignore"`
+	// Fields is the top-level fields compared like objdiff.FieldsAnnotation
+	Fields []string `json:"fields"`
+	// Sensitive is the keys of the payloads redacted like objdiff.SensitiveAnnotation
+	Sensitive []string `json:"sensitive"`
+}
+
+// IsSidecar tells if the file is a sidecar rather than a manifest.
+func IsSidecar(path string) bool {
+	name := filepath.Base(path)
+	return name == DirSidecar || strings.HasSuffix(name, ".difftool.yaml")
+}
+
+// sidecarPaths returns the paths of the sidecars of the manifest in the order of their precedence.
+func sidecarPaths(manifest string) []string {
+	base := strings.TrimSuffix(manifest, filepath.Ext(manifest))
+	return []string{base + ".difftool.yaml", filepath.Join(filepath.Dir(manifest), DirSidecar)}
+}
+
+// LoadSidecar reads the sidecar of the manifest, which is nil if there's none.
+func LoadSidecar(manifest string) (*Sidecar, error) {
+	for _, path := range sidecarPaths(manifest) {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		var s Sidecar
+		if err := yaml.Unmarshal(data, &s); err != nil {
+			return nil, errors.Wrapf(err, "couldn't parse %s", path)
+		}
+		return &s, nil
+	}
+	return nil, nil
+}
+
+// Apply annotates the object and the items of a list with the options, which the annotations already set override.
+func (s *Sidecar) Apply(obj *objdiff.Object) {
+	if s == nil {
+		return
+	}
+	annotate := func(o *objdiff.Object, key string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		if _, ok := o.Annotations[key]; ok {
+			return
+		}
+		if o.Annotations == nil {
+			o.Annotations = make(map[string]string)
+		}
+		o.Annotations[key] = strings.Join(values, ",")
+	}
+	for _, o := range append([]*objdiff.Object{obj}, obj.Items...) {
+		annotate(o, objdiff.IgnoreAnnotation, s.Ignore)
+		annotate(o, objdiff.FieldsAnnotation, s.Fields)
+		annotate(o, objdiff.SensitiveAnnotation, s.Sensitive)
+	}
+}
+
+// loadSidecar reads the sidecar of the manifest and applies it to the objects.
+func loadSidecar(manifest string, objs []*objdiff.Object) error {
+	s, err := LoadSidecar(manifest)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, o := range objs {
+		s.Apply(o)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type operator string
+
+const (
+	opEQ operator = "="
+	opGT operator = ">"
+	opGE operator = ">="
+	opLT operator = "<"
+	opLE operator = "<="
+)
+
+// rxSuffix matches the pre-release and build suffixes like "-rc.1" and "+build.5"
+var rxSuffix = regexp.MustCompile(`^[-+][0-9A-Za-z.+-]+$`)
+
+// longer operators come first so that ">=" isn't taken as ">"
+var operators = []operator{opGE, opLE, opGT, opLT, opEQ}
+
+type condition struct {
+	op      operator
+	version *Version
+}
+
+func (c condition) matches(v *Version) bool {
+	switch c.op {
+	case opGT:
+		return c.version.Less(v)
+	case opGE:
+		return !v.Less(c.version)
+	case opLT:
+		return v.Less(c.version)
+	case opLE:
+		return !c.version.Less(v)
+	}
+	return !v.Less(c.version) && !c.version.Less(v)
+}
+
+// Constraint is a set of conditions on a version, all of which must be satisfied.
+type Constraint struct {
+	conditions []condition
+}
+
+// ParseConstraint parses conditions separated by commas or spaces like ">=1.20.0, <1.25.0".
+// A condition without an operator is taken as "=". Suffixes of versions must be pre-release or build ones
+// starting with "-" or "+", and they're ignored in comparison.
+func ParseConstraint(s string) (*Constraint, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty constraint %q", s)
+	}
+
+	c := &Constraint{}
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		// allow a space between the operator and the version like ">= 1.20.0"
+		if isOperator(f) && i+1 < len(fields) {
+			i++
+			f += fields[i]
+		}
+		op := opEQ
+		for _, o := range operators {
+			if strings.HasPrefix(f, string(o)) {
+				op = o
+				break
+			}
+		}
+		v, err := ParseVersion(strings.TrimPrefix(f, string(op)))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse constraint %q: %w", s, err)
+		}
+		// the lenient version takes anything after the numbers as the suffix like "<1.25.0" of ">1.20.0<1.25.0"
+		if v.Suffix != "" && !rxSuffix.MatchString(v.Suffix) {
+			return nil, fmt.Errorf("could not parse constraint %q: %q isn't a pre-release or build suffix", s, v.Suffix)
+		}
+		c.conditions = append(c.conditions, condition{op: op, version: v})
+	}
+	return c, nil
+}
+
+func isOperator(s string) bool {
+	for _, o := range operators {
+		if s == string(o) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Constraint) Matches(v *Version) bool {
+	for _, cond := range c.conditions {
+		if !cond.matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Constraint) String() string {
+	conds := make([]string, 0, len(c.conditions))
+	for _, cond := range c.conditions {
+		conds = append(conds, string(cond.op)+cond.version.String())
+	}
+	return strings.Join(conds, ", ")
+}
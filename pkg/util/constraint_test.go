@@ -0,0 +1,62 @@
+package util
+
+import "testing"
+
+func TestParseConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		want       string
+		matches    []string
+		mismatches []string
+	}{
+		{constraint: "1.25.0", want: "=1.25.0", matches: []string{"1.25.0", "v1.25.0-rc1"}, mismatches: []string{"1.25.1", "1.24.0"}},
+		{constraint: "=1.25", want: "=1.25.0", matches: []string{"1.25.0"}, mismatches: []string{"1.26.0"}},
+		{constraint: ">1.20.0", want: ">1.20.0", matches: []string{"1.20.1"}, mismatches: []string{"1.20.0", "1.19.9"}},
+		{constraint: ">=1.20.0", want: ">=1.20.0", matches: []string{"1.20.0", "1.21.0"}, mismatches: []string{"1.19.9"}},
+		{constraint: "<1.25.0", want: "<1.25.0", matches: []string{"1.24.9"}, mismatches: []string{"1.25.0"}},
+		{constraint: "<=1.25.0", want: "<=1.25.0", matches: []string{"1.25.0", "1.24.0"}, mismatches: []string{"1.25.1"}},
+		{constraint: ">= 1.20", want: ">=1.20.0", matches: []string{"1.20.0"}, mismatches: []string{"1.19.0"}},
+		{constraint: ">=1.20.0, <1.25.0", want: ">=1.20.0, <1.25.0", matches: []string{"1.20.0", "1.24.9"}, mismatches: []string{"1.19.9", "1.25.0"}},
+		{constraint: ">=1.20.0 <1.25.0", want: ">=1.20.0, <1.25.0", matches: []string{"1.22.0"}, mismatches: []string{"1.25.0"}},
+		{constraint: ">= 1.20, < 1.25", want: ">=1.20.0, <1.25.0", matches: []string{"1.22.0"}, mismatches: []string{"1.25.0"}},
+		{constraint: ">=1.20.0-rc.1+build.5", want: ">=1.20.0-rc.1+build.5", matches: []string{"1.20.0"}, mismatches: []string{"1.19.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			c, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.String() != tt.want {
+				t.Errorf("ParseConstraint(%q) = %s, want %s", tt.constraint, c, tt.want)
+			}
+			for _, in := range tt.matches {
+				if !c.Matches(mustParseVersion(t, in)) {
+					t.Errorf("%s doesn't match %s", in, c)
+				}
+			}
+			for _, in := range tt.mismatches {
+				if c.Matches(mustParseVersion(t, in)) {
+					t.Errorf("%s matches %s", in, c)
+				}
+			}
+		})
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, in := range []string{"", " , ", ">", ">=x", "1.2.3x", ">1.20.0<1.25.0", ">=1.20.0;<1.25", "=>1.20.0"} {
+		if c, err := ParseConstraint(in); err == nil {
+			t.Errorf("ParseConstraint(%q) = %s, want an error", in, c)
+		}
+	}
+}
+
+func mustParseVersion(t *testing.T, vsn string) *Version {
+	t.Helper()
+	v, err := ParseVersion(vsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
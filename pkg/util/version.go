@@ -7,7 +7,11 @@ import (
 	"strings"
 )
 
-var rxVersion = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)(.*)`)
+// a leading "v" is allowed as in git tags and kubernetes releases
+var (
+	rxVersion        = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(.*)`)
+	rxLenientVersion = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(.*)`)
+)
 
 type Version struct {
 	V      [3]uint32
@@ -24,8 +28,18 @@ func (v *Version) Less(r *Version) bool {
 		(v.V[0] == r.V[0] && v.V[1] == r.V[1] && v.V[2] < r.V[2])
 }
 
+// ParseVersion parses a version. The minor and patch versions are 0 if omitted, e.g. "1.25" is "1.25.0".
 func ParseVersion(vsn string) (*Version, error) {
-	m := rxVersion.FindStringSubmatch(strings.TrimSpace(vsn))
+	return parseVersion(rxLenientVersion, vsn)
+}
+
+// ParseVersionStrict parses a version which must have the major, minor and patch versions.
+func ParseVersionStrict(vsn string) (*Version, error) {
+	return parseVersion(rxVersion, vsn)
+}
+
+func parseVersion(rx *regexp.Regexp, vsn string) (*Version, error) {
+	m := rx.FindStringSubmatch(strings.TrimSpace(vsn))
 	if m == nil {
 		return nil, fmt.Errorf("could not parse version %q", vsn)
 	}
@@ -35,6 +49,9 @@ func ParseVersion(vsn string) (*Version, error) {
 	}
 
 	for i := 0; i < 3; i++ {
+		if m[i+1] == "" {
+			continue
+		}
 		d, err := strconv.ParseUint(m[i+1], 10, 32)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,40 @@
+package util
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Version
+	}{
+		{in: "1.2.3", want: Version{V: [3]uint32{1, 2, 3}}},
+		{in: "v1.2.3", want: Version{V: [3]uint32{1, 2, 3}}},
+		{in: "v1.2.3-rc1", want: Version{V: [3]uint32{1, 2, 3}, Suffix: "-rc1"}},
+		{in: "4.14.0-0.nightly-2023-11-28", want: Version{V: [3]uint32{4, 14, 0}, Suffix: "-0.nightly-2023-11-28"}},
+		{in: "1.25", want: Version{V: [3]uint32{1, 25, 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseVersion(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Errorf("ParseVersion(%q) = %+v, want %+v", tt.in, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVersionStrict(t *testing.T) {
+	for _, in := range []string{"1.2.3", "v1.2.3", "v1.2.3-rc1"} {
+		if _, err := ParseVersionStrict(in); err != nil {
+			t.Errorf("ParseVersionStrict(%q) fails: %v", in, err)
+		}
+	}
+	for _, in := range []string{"", "v", "1.25", "vv1.2.3", "x1.2.3"} {
+		if v, err := ParseVersionStrict(in); err == nil {
+			t.Errorf("ParseVersionStrict(%q) = %v, want an error", in, v)
+		}
+	}
+}